@@ -0,0 +1,121 @@
+// sqlinternals for github.com/go-sql-driver/mysql - retrieve column metadata from sql.*Row / sql.*Rows
+//
+// Copyright 2013 Arne Hormann. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mysqlinternals
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// ArrowType is the name of an Apache Arrow data type.
+type ArrowType string
+
+const (
+	ArrowInt8       ArrowType = "int8"
+	ArrowInt16      ArrowType = "int16"
+	ArrowInt32      ArrowType = "int32"
+	ArrowInt64      ArrowType = "int64"
+	ArrowUint8      ArrowType = "uint8"
+	ArrowUint16     ArrowType = "uint16"
+	ArrowUint32     ArrowType = "uint32"
+	ArrowUint64     ArrowType = "uint64"
+	ArrowFloat32    ArrowType = "float32"
+	ArrowFloat64    ArrowType = "float64"
+	ArrowUtf8       ArrowType = "utf8"
+	ArrowBinary     ArrowType = "binary"
+	ArrowTimestamp  ArrowType = "timestamp"
+	ArrowDecimal128 ArrowType = "decimal128"
+)
+
+// ArrowField describes a column as a field of an Apache Arrow schema.
+// Precision and Scale are only set for ArrowDecimal128.
+type ArrowField struct {
+	Name      string
+	Type      ArrowType
+	Nullable  bool
+	Precision int32
+	Scale     int32
+}
+
+// decimal128 holds at most 38 digits
+const arrowMaxPrecision = 38
+
+// ArrowFields maps the columns to the fields of an Apache Arrow schema.
+//
+// Integers map to the signed or unsigned Arrow integer of the size of SmallestGoIntType,
+// FLOAT and DOUBLE to float32 and float64, DECIMAL to decimal128 with the precision
+// and scale of DecimalSize. DATE, DATETIME and TIMESTAMP map to timestamp;
+// YEAR maps to int16 and TIME, which may exceed a day or be negative, to utf8 like
+// the other textual types. Binary types including BIT, GEOMETRY and VECTOR map to binary.
+// Fields are nullable unless the column is marked NOT NULL.
+// It fails for NULL columns, unknown types and DECIMALs with more than 38 digits.
+func ArrowFields(cols []Column) ([]ArrowField, error) {
+	fields := make([]ArrowField, len(cols))
+	for i, col := range cols {
+		field, err := arrowField(col)
+		if err != nil {
+			return nil, err
+		}
+		fields[i] = field
+	}
+	return fields, nil
+}
+
+func arrowField(col Column) (ArrowField, error) {
+	field := ArrowField{Name: col.Name(), Nullable: !col.IsNotNull()}
+	switch col.Category() {
+	case CategoryInteger:
+		intType, err := col.SmallestGoIntType()
+		if err != nil {
+			return field, err
+		}
+		field.Type = arrowIntTypes[intType]
+	case CategoryFloat:
+		field.Type = ArrowFloat64
+		if builtinTypeOf(col) == "FLOAT" {
+			field.Type = ArrowFloat32
+		}
+	case CategoryDecimal:
+		precision, scale, _ := col.DecimalSize()
+		if precision > arrowMaxPrecision {
+			return field, mysqlError("ArrowFields: precision " + strconv.FormatInt(precision, 10) +
+				" of column " + strconv.Quote(col.Name()) + " exceeds decimal128")
+		}
+		field.Type = ArrowDecimal128
+		field.Precision, field.Scale = int32(precision), int32(scale)
+	case CategoryTemporal:
+		switch builtinTypeOf(col) {
+		case "YEAR":
+			field.Type = ArrowInt16
+		case "TIME":
+			field.Type = ArrowUtf8
+		default:
+			field.Type = ArrowTimestamp
+		}
+	case CategoryText, CategoryEnum, CategorySet, CategoryJSON:
+		field.Type = ArrowUtf8
+	case CategoryBlob, CategoryBit, CategoryGeometry, CategoryVector:
+		field.Type = ArrowBinary
+	default:
+		return field, mysqlError("ArrowFields: no Arrow type for column " + strconv.Quote(col.Name()) +
+			" of type " + col.MysqlType())
+	}
+	return field, nil
+}
+
+var arrowIntTypes = map[reflect.Type]ArrowType{
+	typeInt8:   ArrowInt8,
+	typeInt16:  ArrowInt16,
+	typeInt32:  ArrowInt32,
+	typeInt64:  ArrowInt64,
+	typeUint8:  ArrowUint8,
+	typeUint16: ArrowUint16,
+	typeUint32: ArrowUint32,
+	typeUint64: ArrowUint64,
+}
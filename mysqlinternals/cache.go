@@ -0,0 +1,61 @@
+// sqlinternals for github.com/go-sql-driver/mysql - retrieve column metadata from sql.*Row / sql.*Rows
+//
+// Copyright 2013 Arne Hormann. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mysqlinternals
+
+import (
+	"sync"
+)
+
+// ColumnCache caches the results of Columns by a key chosen by the caller,
+// e.g. the query of a prepared statement.
+//
+// The metadata of a statement does not change between executions,
+// so the inspection is only done for the first result of each key.
+// A ColumnCache must not be copied after first use, it is safe for concurrent use.
+// The zero value is an empty cache ready to use.
+type ColumnCache struct {
+	mutex   sync.RWMutex
+	columns map[string][]Column
+	// inspect retrieves the columns on a cache miss, Columns is used if nil
+	inspect func(rowOrRows interface{}) ([]Column, error)
+}
+
+// Get returns the columns cached for key.
+// On a miss, the columns are retrieved from rowOrRows and cached if there is no error.
+// The returned slice is shared and must not be modified.
+func (c *ColumnCache) Get(key string, rowOrRows interface{}) ([]Column, error) {
+	c.mutex.RLock()
+	cols, ok := c.columns[key]
+	c.mutex.RUnlock()
+	if ok {
+		return cols, nil
+	}
+	inspect := c.inspect
+	if inspect == nil {
+		inspect = Columns
+	}
+	cols, err := inspect(rowOrRows)
+	if err != nil {
+		return nil, err
+	}
+	c.mutex.Lock()
+	if c.columns == nil {
+		c.columns = make(map[string][]Column)
+	}
+	c.columns[key] = cols
+	c.mutex.Unlock()
+	return cols, nil
+}
+
+// Delete removes the columns cached for key.
+func (c *ColumnCache) Delete(key string) {
+	c.mutex.Lock()
+	delete(c.columns, key)
+	c.mutex.Unlock()
+}
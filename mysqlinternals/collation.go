@@ -0,0 +1,257 @@
+// sqlinternals for github.com/go-sql-driver/mysql - retrieve column metadata from sql.*Row / sql.*Rows
+//
+// Copyright 2013 Arne Hormann. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mysqlinternals
+
+import (
+	"strings"
+)
+
+// names of the collations with ids below 256, see SHOW COLLATION
+var collationNames = map[uint8]string{
+	1:   "big5_chinese_ci",
+	2:   "latin2_czech_cs",
+	3:   "dec8_swedish_ci",
+	4:   "cp850_general_ci",
+	5:   "latin1_german1_ci",
+	6:   "hp8_english_ci",
+	7:   "koi8r_general_ci",
+	8:   "latin1_swedish_ci",
+	9:   "latin2_general_ci",
+	10:  "swe7_swedish_ci",
+	11:  "ascii_general_ci",
+	12:  "ujis_japanese_ci",
+	13:  "sjis_japanese_ci",
+	14:  "cp1251_bulgarian_ci",
+	15:  "latin1_danish_ci",
+	16:  "hebrew_general_ci",
+	18:  "tis620_thai_ci",
+	19:  "euckr_korean_ci",
+	20:  "latin7_estonian_cs",
+	21:  "latin2_hungarian_ci",
+	22:  "koi8u_general_ci",
+	23:  "cp1251_ukrainian_ci",
+	24:  "gb2312_chinese_ci",
+	25:  "greek_general_ci",
+	26:  "cp1250_general_ci",
+	27:  "latin2_croatian_ci",
+	28:  "gbk_chinese_ci",
+	29:  "cp1257_lithuanian_ci",
+	30:  "latin5_turkish_ci",
+	31:  "latin1_german2_ci",
+	32:  "armscii8_general_ci",
+	33:  "utf8_general_ci",
+	34:  "cp1250_czech_cs",
+	35:  "ucs2_general_ci",
+	36:  "cp866_general_ci",
+	37:  "keybcs2_general_ci",
+	38:  "macce_general_ci",
+	39:  "macroman_general_ci",
+	40:  "cp852_general_ci",
+	41:  "latin7_general_ci",
+	42:  "latin7_general_cs",
+	43:  "macce_bin",
+	44:  "cp1250_croatian_ci",
+	45:  "utf8mb4_general_ci",
+	46:  "utf8mb4_bin",
+	47:  "latin1_bin",
+	48:  "latin1_general_ci",
+	49:  "latin1_general_cs",
+	50:  "cp1251_bin",
+	51:  "cp1251_general_ci",
+	52:  "cp1251_general_cs",
+	53:  "macroman_bin",
+	54:  "utf16_general_ci",
+	55:  "utf16_bin",
+	56:  "utf16le_general_ci",
+	57:  "cp1256_general_ci",
+	58:  "cp1257_bin",
+	59:  "cp1257_general_ci",
+	60:  "utf32_general_ci",
+	61:  "utf32_bin",
+	62:  "utf16le_bin",
+	63:  "binary",
+	64:  "armscii8_bin",
+	65:  "ascii_bin",
+	66:  "cp1250_bin",
+	67:  "cp1256_bin",
+	68:  "cp866_bin",
+	69:  "dec8_bin",
+	70:  "greek_bin",
+	71:  "hebrew_bin",
+	72:  "hp8_bin",
+	73:  "keybcs2_bin",
+	74:  "koi8r_bin",
+	75:  "koi8u_bin",
+	77:  "latin2_bin",
+	78:  "latin5_bin",
+	79:  "latin7_bin",
+	80:  "cp850_bin",
+	81:  "cp852_bin",
+	82:  "swe7_bin",
+	83:  "utf8_bin",
+	84:  "big5_bin",
+	85:  "euckr_bin",
+	86:  "gb2312_bin",
+	87:  "gbk_bin",
+	88:  "sjis_bin",
+	89:  "tis620_bin",
+	90:  "ucs2_bin",
+	91:  "ujis_bin",
+	92:  "geostd8_general_ci",
+	93:  "geostd8_bin",
+	94:  "latin1_spanish_ci",
+	95:  "cp932_japanese_ci",
+	96:  "cp932_bin",
+	97:  "eucjpms_japanese_ci",
+	98:  "eucjpms_bin",
+	99:  "cp1250_polish_ci",
+	192: "utf8_unicode_ci",
+	224: "utf8mb4_unicode_ci",
+	246: "utf8mb4_unicode_520_ci",
+	247: "utf8mb4_vietnamese_ci",
+	248: "gb18030_chinese_ci",
+	249: "gb18030_bin",
+	255: "utf8mb4_0900_ai_ci",
+}
+
+// The driver only keeps the lower byte of the collation id. MySQL 8.0 assigns ids up to 323,
+// the ids 256 to 323 of its utf8mb4 collations are reported as 0 to 67.
+const maxAliasedCharSet = 323 - 256
+
+// TrustCollationIDs makes the collation methods trust the ids 0 to 67, e.g. Collation and
+// IsCaseSensitive report them as unknown by default because MySQL 8.0 also reports its
+// utf8mb4 collations 256 to 323 with them. The default collations of latin1, utf8mb3 and
+// utf8mb4 and binary are always trusted.
+// Set it if the server does not use these collations, e.g. for MySQL 5.7 or MariaDB.
+var TrustCollationIDs = false
+
+// default collations of the common character sets, they are assumed for their ids
+// although MySQL 8.0 reports utf8mb4 collations above 255 with them, too
+var defaultCollations = map[uint8]bool{
+	8:  true, // latin1_swedish_ci
+	33: true, // utf8_general_ci
+	45: true, // utf8mb4_general_ci
+	63: true, // binary
+}
+
+// collationAliased reports whether a collation with an id above 255 may be reported as id.
+func collationAliased(id uint8) bool {
+	return id <= maxAliasedCharSet && !TrustCollationIDs && !defaultCollations[id]
+}
+
+// name of the collation, unknown if an id above 255 may be reported as the same id,
+// see TrustCollationIDs
+func (f mysqlField) Collation() (string, bool) {
+	if collationAliased(f.charSet) {
+		return "", false
+	}
+	name, ok := collationNames[f.charSet]
+	return name, ok
+}
+
+// characterSet returns the character set of the collation with utf8 written as utf8mb3.
+// It is also known for aliased ids of utf8mb4 collations, all collations aliasing them use utf8mb4.
+func (f mysqlField) characterSet() (string, bool) {
+	name, ok := collationNames[f.charSet]
+	if !ok {
+		return "", false
+	}
+	charset := name
+	if i := strings.IndexByte(name, '_'); i > 0 {
+		charset = name[:i]
+	}
+	if collationAliased(f.charSet) && charset != "utf8mb4" {
+		return "", false
+	}
+	if charset == "utf8" {
+		charset = "utf8mb3"
+	}
+	return charset, true
+}
+
+// case sensitivity of the collation for textual columns
+func (f mysqlField) IsCaseSensitive() (bool, bool) {
+	switch f.Category() {
+	case CategoryText, CategoryBlob, CategoryEnum, CategorySet, CategoryJSON:
+	default:
+		return false, false
+	}
+	name, ok := f.Collation()
+	switch {
+	case f.IsBinary():
+		// binary strings and the _bin collations compare bytes
+		return true, true
+	case !ok:
+		return false, false
+	case name == "binary", strings.HasSuffix(name, "_bin"), strings.HasSuffix(name, "_cs"):
+		return true, true
+	case strings.HasSuffix(name, "_ci"):
+		return false, true
+	}
+	return false, false
+}
+
+// normalizedCollation returns the collation name with utf8 written as utf8mb3.
+func (f mysqlField) normalizedCollation() (string, bool) {
+	name, ok := f.Collation()
+	if ok && strings.HasPrefix(name, "utf8_") {
+		name = "utf8mb3_" + strings.TrimPrefix(name, "utf8_")
+	}
+	return name, ok
+}
+
+// charsetClause returns the CHARACTER SET and COLLATE clause for known non-binary character sets,
+// COLLATE is omitted if only the character set is known.
+func (f mysqlField) charsetClause() string {
+	charset, ok := f.characterSet()
+	if !ok || charset == "binary" {
+		return ""
+	}
+	if name, ok := f.normalizedCollation(); ok {
+		return " CHARACTER SET " + charset + " COLLATE " + name
+	}
+	return " CHARACTER SET " + charset
+}
+
+// maximum bytes per character of the multibyte character sets, all others use one byte
+var multibyteCharsets = map[string]int64{
+	"big5":    2,
+	"cp932":   2,
+	"eucjpms": 3,
+	"euckr":   2,
+	"gb18030": 4,
+	"gb2312":  2,
+	"gbk":     2,
+	"sjis":    2,
+	"ucs2":    2,
+	"ujis":    3,
+	"utf16":   4,
+	"utf16le": 4,
+	"utf32":   4,
+	"utf8":    3,
+	"utf8mb3": 3,
+	"utf8mb4": 4,
+}
+
+// length of textual columns in characters
+func (f mysqlField) CharLength() (int64, bool) {
+	if !f.IsText() {
+		return 0, false
+	}
+	charset, ok := f.characterSet()
+	if !ok || charset == "binary" {
+		// binary strings have no characters
+		return 0, false
+	}
+	maxBytes := int64(1)
+	if n, ok := multibyteCharsets[charset]; ok {
+		maxBytes = n
+	}
+	return int64(f.length) / maxBytes, true
+}
@@ -0,0 +1,75 @@
+// sqlinternals for github.com/go-sql-driver/mysql - retrieve column metadata from sql.*Row / sql.*Rows
+//
+// Copyright 2013 Arne Hormann. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mysqlinternals
+
+// Config is a snapshot of the package level options: Logger, FieldNamer, UnboundedColumnBytes,
+// TrustCollationIDs and the registrations of RegisterFieldType and SetTypeNameOverride.
+// It can't be modified, create it with Snapshot and apply it with Restore.
+type Config struct {
+	logger               func(message string)
+	fieldNamer           func(columnName string) string
+	unboundedColumnBytes int64
+	trustCollationIDs    bool
+	fieldTypes           map[uint8]fieldTypeInfo
+	typeNameOverrides    map[uint8]TypeNameOverride
+	// set by Snapshot, false for the zero Config
+	taken bool
+}
+
+// Snapshot returns the current package level options.
+//
+// Layouts registered with RegisterLayout are not part of it, they are validated once per type.
+func Snapshot() Config {
+	return Config{
+		logger:               Logger,
+		fieldNamer:           FieldNamer,
+		unboundedColumnBytes: UnboundedColumnBytes,
+		trustCollationIDs:    TrustCollationIDs,
+		fieldTypes:           registeredFieldTypes(),
+		typeNameOverrides:    currentTypeNameOverrides(),
+		taken:                true,
+	}
+}
+
+// the options before any changes
+var defaultConfig = Snapshot()
+
+// Restore replaces the package level options with those of a Snapshot,
+// e.g. to undo changes in tests. The zero Config restores the defaults.
+// Like the options themselves, it must not be called concurrently with their use.
+func Restore(c Config) {
+	if !c.taken {
+		c = defaultConfig
+	}
+	Logger = c.logger
+	FieldNamer = c.fieldNamer
+	UnboundedColumnBytes = c.unboundedColumnBytes
+	TrustCollationIDs = c.trustCollationIDs
+	// the maps are never modified, they are replaced on changes
+	fieldTypeMutex.Lock()
+	fieldTypeRegistry.Store(c.fieldTypes)
+	typeNameOverrides.Store(c.typeNameOverrides)
+	fieldTypeMutex.Unlock()
+}
+
+func copyFieldTypes(src map[uint8]fieldTypeInfo) map[uint8]fieldTypeInfo {
+	dst := make(map[uint8]fieldTypeInfo, len(src))
+	for code, t := range src {
+		dst[code] = t
+	}
+	return dst
+}
+
+func copyTypeNameOverrides(src map[uint8]TypeNameOverride) map[uint8]TypeNameOverride {
+	dst := make(map[uint8]TypeNameOverride, len(src))
+	for code, override := range src {
+		dst[code] = override
+	}
+	return dst
+}
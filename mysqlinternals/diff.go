@@ -0,0 +1,83 @@
+// sqlinternals for github.com/go-sql-driver/mysql - retrieve column metadata from sql.*Row / sql.*Rows
+//
+// Copyright 2013 Arne Hormann. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mysqlinternals
+
+// ChangeKind is the kind of a change reported by DiffColumns.
+type ChangeKind int
+
+const (
+	// the column only exists in the new columns
+	ColumnAdded ChangeKind = iota + 1
+	// the column only exists in the old columns
+	ColumnRemoved
+	// the column at the same position has a different name, but the same type
+	ColumnRenamed
+	// the column with the same name has a different type
+	ColumnRetyped
+	// the column with the same name changed between NULL and NOT NULL
+	ColumnNullabilityChanged
+)
+
+// ColumnChange is a column level difference between two []Column.
+type ColumnChange struct {
+	Kind ChangeKind
+	// Old is nil for added columns
+	Old Column
+	// New is nil for removed columns
+	New Column
+}
+
+// DiffColumns reports the differences between the old and the new columns.
+//
+// Columns are matched by name. Unmatched columns at the same position with the same type
+// and nullability are reported as renamed, all others as removed and added.
+// A matched column may be reported as both retyped and with changed nullability.
+// Changes are ordered by the position in new, removed columns follow in the order of old.
+func DiffColumns(old, new []Column) []ColumnChange {
+	oldIndex := make(map[string]int, len(old))
+	for i, col := range old {
+		oldIndex[col.Name()] = i
+	}
+	newNames := make(map[string]bool, len(new))
+	for _, col := range new {
+		newNames[col.Name()] = true
+	}
+	var changes []ColumnChange
+	matched := make([]bool, len(old))
+	for i, col := range new {
+		if j, ok := oldIndex[col.Name()]; ok && !matched[j] {
+			matched[j] = true
+			if !sameType(old[j], col) {
+				changes = append(changes, ColumnChange{Kind: ColumnRetyped, Old: old[j], New: col})
+			}
+			if old[j].IsNotNull() != col.IsNotNull() {
+				changes = append(changes, ColumnChange{Kind: ColumnNullabilityChanged, Old: old[j], New: col})
+			}
+			continue
+		}
+		if i < len(old) && !newNames[old[i].Name()] && !matched[i] &&
+			sameType(old[i], col) && old[i].IsNotNull() == col.IsNotNull() {
+			matched[i] = true
+			changes = append(changes, ColumnChange{Kind: ColumnRenamed, Old: old[i], New: col})
+			continue
+		}
+		changes = append(changes, ColumnChange{Kind: ColumnAdded, New: col})
+	}
+	for j, col := range old {
+		if !matched[j] {
+			changes = append(changes, ColumnChange{Kind: ColumnRemoved, Old: col})
+		}
+	}
+	return changes
+}
+
+// sameType reports whether a and b have the same MySQL type and signedness.
+func sameType(a, b Column) bool {
+	return a.Category() == b.Category() && a.MysqlType() == b.MysqlType() && a.IsUnsigned() == b.IsUnsigned()
+}
@@ -0,0 +1,104 @@
+// sqlinternals for github.com/go-sql-driver/mysql - retrieve column metadata from sql.*Row / sql.*Rows
+//
+// Copyright 2013 Arne Hormann. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mysqlinternals
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Dump describes all parsed column metadata of sql.Rows or sql.Row for debugging,
+// one line per column with the raw type code, flags, length, decimals and charset.
+//
+// github.com/go-sql-driver/mysql discards the raw column definition packets after
+// parsing them, so they can't be retrieved. Dump shows everything the driver keeps.
+// It returns the error message if the columns are not available.
+func Dump(rowOrRows interface{}) string {
+	cols, err := Columns(rowOrRows)
+	if err != nil {
+		return err.Error()
+	}
+	var dump strings.Builder
+	for i, col := range cols {
+		f, ok := col.(mysqlField)
+		if !ok {
+			fmt.Fprintf(&dump, "%d: %q %s\n", i, col.Name(), col.MysqlType())
+			continue
+		}
+		fmt.Fprintf(&dump, "%d: %q.%q type=%#02x (%s) flags=%#04x length=%d decimals=%d charset=%d\n",
+			i, f.tableName, f.name, f.fieldType, f.MysqlType(), uint16(f.flags), f.length, f.decimals, f.charSet)
+	}
+	return dump.String()
+}
+
+// Describe returns an aligned table of the columns of sql.Rows or sql.Row like the output
+// of DESCRIBE in the mysql client, with one row per column and the columns
+// Name, Type (MysqlType and UNSIGNED), Go Type (ReflectSqlType), Null, Key and Extra.
+// Go Type is empty for columns without a matching Go type.
+func Describe(rowOrRows interface{}) (string, error) {
+	cols, err := Columns(rowOrRows)
+	if err != nil {
+		return "", err
+	}
+	table := [][]string{{"Name", "Type", "Go Type", "Null", "Key", "Extra"}}
+	for _, col := range cols {
+		mysqlType := col.MysqlType()
+		if col.IsUnsigned() {
+			mysqlType += " UNSIGNED"
+		}
+		var goType string
+		if t, err := col.ReflectSqlType(false); err == nil {
+			goType = t.String()
+		}
+		null := "YES"
+		if col.IsNotNull() {
+			null = "NO"
+		}
+		var extra string
+		if col.IsAutoIncrement() {
+			extra = "auto_increment"
+		}
+		table = append(table, []string{col.Name(), mysqlType, goType, null, keyNames[col.KeyKind()], extra})
+	}
+	widths := make([]int, len(table[0]))
+	for _, row := range table {
+		for i, cell := range row {
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	var border strings.Builder
+	for _, width := range widths {
+		border.WriteString("+" + strings.Repeat("-", width+2))
+	}
+	border.WriteString("+\n")
+	var desc strings.Builder
+	desc.WriteString(border.String())
+	for r, row := range table {
+		for i, cell := range row {
+			desc.WriteString("| " + cell + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)+1))
+		}
+		desc.WriteString("|\n")
+		if r == 0 {
+			desc.WriteString(border.String())
+		}
+	}
+	desc.WriteString(border.String())
+	return desc.String(), nil
+}
+
+// keys in the notation of DESCRIBE
+var keyNames = map[KeyKind]string{
+	KeyNone:     "",
+	KeyPrimary:  "PRI",
+	KeyUnique:   "UNI",
+	KeyMultiple: "MUL",
+}
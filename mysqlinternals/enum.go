@@ -0,0 +1,50 @@
+// sqlinternals for github.com/go-sql-driver/mysql - retrieve column metadata from sql.*Row / sql.*Rows
+//
+// Copyright 2013 Arne Hormann. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mysqlinternals
+
+import (
+	"go/format"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// EnumGoType returns Go source declaring typeName as a string type with one exported
+// constant per member of the ENUM column col, e.g. StatusActive for the member "active"
+// of the type Status. The constant names are derived from the members by FieldNamer.
+//
+// The protocol does not transmit the members of ENUM columns, pass them in their order
+// of declaration, e.g. read from COLUMN_TYPE in information_schema.COLUMNS.
+// It fails for other columns, invalid type names and members mapped to the same constant.
+func EnumGoType(col Column, typeName string, members []string) (string, error) {
+	if col.Category() != CategoryEnum {
+		return "", mysqlError("EnumGoType: column " + strconv.Quote(col.Name()) + " is no ENUM")
+	}
+	if !token.IsIdentifier(typeName) {
+		return "", mysqlError("EnumGoType: invalid type name " + strconv.Quote(typeName))
+	}
+	var src strings.Builder
+	src.WriteString("// " + typeName + " is a member of the ENUM column " + col.Name() + ".\n")
+	src.WriteString("type " + typeName + " string\n\nconst (\n")
+	used := make(map[string]bool, len(members))
+	for _, member := range members {
+		name := typeName + FieldNamer(member)
+		if !token.IsIdentifier(name) || used[name] {
+			return "", mysqlError("EnumGoType: no distinct constant name for member " + strconv.Quote(member))
+		}
+		used[name] = true
+		src.WriteString(name + " " + typeName + " = " + strconv.Quote(member) + "\n")
+	}
+	src.WriteString(")\n")
+	formatted, err := format.Source([]byte(src.String()))
+	if err != nil {
+		return "", err
+	}
+	return string(formatted), nil
+}
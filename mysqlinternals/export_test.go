@@ -0,0 +1,263 @@
+// sqlinternals for github.com/go-sql-driver/mysql - retrieve column metadata from sql.*Row / sql.*Rows
+//
+// Copyright 2013 Arne Hormann. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mysqlinternals
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// fakeDB is a database/sql driver returning prepared driver.Rows for every query.
+type fakeDB struct {
+	rows driver.Rows
+}
+
+func (f *fakeDB) Connect(ctx context.Context) (driver.Conn, error) { return f, nil }
+func (f *fakeDB) Driver() driver.Driver                            { return f }
+func (f *fakeDB) Open(name string) (driver.Conn, error)            { return f, nil }
+func (f *fakeDB) Prepare(query string) (driver.Stmt, error)        { return f, nil }
+func (f *fakeDB) Begin() (driver.Tx, error)                        { return nil, driver.ErrSkip }
+func (f *fakeDB) Close() error                                     { return nil }
+func (f *fakeDB) NumInput() int                                    { return -1 }
+func (f *fakeDB) Exec(args []driver.Value) (driver.Result, error)  { return nil, driver.ErrSkip }
+func (f *fakeDB) Query(args []driver.Value) (driver.Rows, error)   { return f.rows, nil }
+
+// fakeQuery runs a query on a fakeDB returning rows.
+func fakeQuery(t testing.TB, rows driver.Rows) *sql.Rows {
+	db := sql.OpenDB(&fakeDB{rows: rows})
+	result, err := db.Query("fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// textRows and binaryRows mimic the driver.Rows of github.com/go-sql-driver/mysql
+type textRows struct {
+	mysqlRows
+	fakeValues
+}
+
+type binaryRows struct {
+	mysqlRows
+	fakeValues
+}
+
+func (r *mysqlRows) Columns() []string {
+	// like the driver, prefer the cached names
+	if r.rs.columnNames != nil {
+		return r.rs.columnNames
+	}
+	names := make([]string, len(r.rs.columns))
+	for i, c := range r.rs.columns {
+		names[i] = c.name
+	}
+	return names
+}
+
+func (r *mysqlRows) Close() error { return nil }
+
+// ColumnTypeScanType reports the scan type like the driver, see driverScanType
+func (r *mysqlRows) ColumnTypeScanType(i int) reflect.Type { return driverScanType(r.rs.columns[i]) }
+
+// scan types of github.com/go-sql-driver/mysql/fields.go
+var (
+	scanTypeFloat32   = reflect.TypeOf(float32(0))
+	scanTypeFloat64   = reflect.TypeOf(float64(0))
+	scanTypeInt8      = reflect.TypeOf(int8(0))
+	scanTypeInt16     = reflect.TypeOf(int16(0))
+	scanTypeInt32     = reflect.TypeOf(int32(0))
+	scanTypeInt64     = reflect.TypeOf(int64(0))
+	scanTypeNullFloat = reflect.TypeOf(sql.NullFloat64{})
+	scanTypeNullInt   = reflect.TypeOf(sql.NullInt64{})
+	scanTypeNullTime  = reflect.TypeOf(mysql.NullTime{})
+	scanTypeUint8     = reflect.TypeOf(uint8(0))
+	scanTypeUint16    = reflect.TypeOf(uint16(0))
+	scanTypeUint32    = reflect.TypeOf(uint32(0))
+	scanTypeUint64    = reflect.TypeOf(uint64(0))
+	scanTypeRawBytes  = reflect.TypeOf(sql.RawBytes{})
+	scanTypeUnknown   = reflect.TypeOf(new(interface{}))
+)
+
+// driverScanType is copied from mysqlField.scanType in github.com/go-sql-driver/mysql/fields.go,
+// keep it in sync with the driver instead of deriving it from ScanType.
+func driverScanType(mf mysqlField) reflect.Type {
+	switch mf.fieldType {
+	case fieldTypeTiny:
+		if mf.flags&flagNotNULL != 0 {
+			if mf.flags&flagUnsigned != 0 {
+				return scanTypeUint8
+			}
+			return scanTypeInt8
+		}
+		return scanTypeNullInt
+
+	case fieldTypeShort, fieldTypeYear:
+		if mf.flags&flagNotNULL != 0 {
+			if mf.flags&flagUnsigned != 0 {
+				return scanTypeUint16
+			}
+			return scanTypeInt16
+		}
+		return scanTypeNullInt
+
+	case fieldTypeInt24, fieldTypeLong:
+		if mf.flags&flagNotNULL != 0 {
+			if mf.flags&flagUnsigned != 0 {
+				return scanTypeUint32
+			}
+			return scanTypeInt32
+		}
+		return scanTypeNullInt
+
+	case fieldTypeLongLong:
+		if mf.flags&flagNotNULL != 0 {
+			if mf.flags&flagUnsigned != 0 {
+				return scanTypeUint64
+			}
+			return scanTypeInt64
+		}
+		return scanTypeNullInt
+
+	case fieldTypeFloat:
+		if mf.flags&flagNotNULL != 0 {
+			return scanTypeFloat32
+		}
+		return scanTypeNullFloat
+
+	case fieldTypeDouble:
+		if mf.flags&flagNotNULL != 0 {
+			return scanTypeFloat64
+		}
+		return scanTypeNullFloat
+
+	case fieldTypeDecimal, fieldTypeNewDecimal, fieldTypeVarChar,
+		fieldTypeBit, fieldTypeEnum, fieldTypeSet, fieldTypeTinyBLOB,
+		fieldTypeMediumBLOB, fieldTypeLongBLOB, fieldTypeBLOB,
+		fieldTypeVarString, fieldTypeString, fieldTypeGeometry, fieldTypeJSON,
+		fieldTypeTime:
+		return scanTypeRawBytes
+
+	case fieldTypeDate, fieldTypeNewDate,
+		fieldTypeTimestamp, fieldTypeDateTime:
+		// NullTime is always returned for more consistent behavior as it can
+		// handle both cases of parseTime regardless if the field is nullable.
+		return scanTypeNullTime
+
+	default:
+		return scanTypeUnknown
+	}
+}
+
+// mysqlStmt mimics the driver.Stmt of github.com/go-sql-driver/mysql
+func (s *mysqlStmt) Close() error                                    { return nil }
+func (s *mysqlStmt) NumInput() int                                   { return s.paramCount }
+func (s *mysqlStmt) Exec(args []driver.Value) (driver.Result, error) { return nil, driver.ErrSkip }
+func (s *mysqlStmt) Query(args []driver.Value) (driver.Rows, error)  { return nil, driver.ErrSkip }
+
+// fakeValues provides the values and further result sets for the fake rows
+type fakeValues struct {
+	values     [][]driver.Value
+	resultSets [][]mysqlField
+	// repeat returns the first row forever
+	repeat bool
+}
+
+func (v *fakeValues) Next(dest []driver.Value) error {
+	if len(v.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, v.values[0])
+	if !v.repeat {
+		v.values = v.values[1:]
+	}
+	return nil
+}
+
+func (r *textRows) HasNextResultSet() bool {
+	return len(r.resultSets) > 0
+}
+
+// NextResultSet replaces the columns like the driver does
+func (r *textRows) NextResultSet() error {
+	if !r.HasNextResultSet() {
+		return io.EOF
+	}
+	r.rs.columns, r.resultSets = r.resultSets[0], r.resultSets[1:]
+	return nil
+}
+
+func (r emptyRows) Columns() []string              { return nil }
+func (r emptyRows) Close() error                   { return nil }
+func (r emptyRows) Next(dest []driver.Value) error { return io.EOF }
+
+// newFakeRows creates driver.Rows with the layout of github.com/go-sql-driver/mysql
+// returning values on Next. They use the binary protocol if binary is set.
+func newFakeRows(binary bool, fields []mysqlField, values ...[]driver.Value) driver.Rows {
+	rows := mysqlRows{rs: resultSet{columns: fields}}
+	if binary {
+		return &binaryRows{rows, fakeValues{values: values}}
+	}
+	return &textRows{rows, fakeValues{values: values}}
+}
+
+// countedRows and uncountedRows are driver.Rows of other drivers
+type countedRows struct {
+	numRows uint32
+}
+
+func (r *countedRows) Columns() []string              { return nil }
+func (r *countedRows) Close() error                   { return nil }
+func (r *countedRows) Next(dest []driver.Value) error { return io.EOF }
+
+type uncountedRows struct {
+	columns []string
+}
+
+func (r *uncountedRows) Columns() []string              { return r.columns }
+func (r *uncountedRows) Close() error                   { return nil }
+func (r *uncountedRows) Next(dest []driver.Value) error { return io.EOF }
+
+// xRows are driver.Rows with a layout unknown to the package, see TestRegisterLayout
+type xRows struct {
+	fields []mysqlField
+}
+
+func (r *xRows) Columns() []string {
+	names := make([]string, len(r.fields))
+	for i, f := range r.fields {
+		names[i] = f.name
+	}
+	return names
+}
+func (r *xRows) Close() error                   { return nil }
+func (r *xRows) Next(dest []driver.Value) error { return io.EOF }
+
+// typedRows implement the optional driver interfaces for column types
+type typedRows struct {
+	uncountedRows
+	typeNames []string
+	nullable  []bool
+	lengths   []int64
+	scales    []int64
+}
+
+func (r *typedRows) ColumnTypeDatabaseTypeName(i int) string { return r.typeNames[i] }
+func (r *typedRows) ColumnTypeNullable(i int) (bool, bool)   { return r.nullable[i], true }
+func (r *typedRows) ColumnTypeLength(i int) (int64, bool)    { return r.lengths[i], r.lengths[i] > 0 }
+func (r *typedRows) ColumnTypePrecisionScale(i int) (int64, int64, bool) {
+	return r.lengths[i], r.scales[i], strings.HasSuffix(r.typeNames[i], "DECIMAL")
+}
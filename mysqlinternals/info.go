@@ -0,0 +1,72 @@
+// sqlinternals for github.com/go-sql-driver/mysql - retrieve column metadata from sql.*Row / sql.*Rows
+//
+// Copyright 2013 Arne Hormann. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mysqlinternals
+
+// ColumnInfo is a copy of the metadata of a column as a value type.
+// It can be stored, compared and serialized without access to the rows.
+type ColumnInfo struct {
+	TableName string
+	Name      string
+	Length    uint32
+	// Flags are the column flags of the protocol, e.g. 1 for NOT NULL
+	Flags uint16
+	// Type is the type code of the protocol, e.g. 3 for INT
+	Type     byte
+	Decimals byte
+	// CharSet is the lower byte of the collation id
+	CharSet uint8
+}
+
+// ColumnDetails retrieves the metadata of the columns of sql.Rows or sql.Row
+// as values with exported fields.
+//
+// It works like Columns; use ColumnInfo.Column for the inspection methods of a column.
+// ReadColumnInfo avoids the allocation of the result.
+func ColumnDetails(rowOrRows interface{}) ([]ColumnInfo, error) {
+	dRows, layout, stage := driverRows(rowOrRows)
+	if stage != StageNone {
+		return nil, columnsError("ColumnDetails", rowOrRows, stage)
+	}
+	cols, err := resultColumns("ColumnDetails", rowOrRows, dRows, layout)
+	if err != nil {
+		return nil, err
+	}
+	details := make([]ColumnInfo, len(cols))
+	for i, col := range cols {
+		// all implementations of Column are mysqlField
+		f, _ := col.(mysqlField)
+		details[i] = f.info()
+	}
+	return details, nil
+}
+
+// Column returns the metadata as Column with all inspection methods.
+func (c ColumnInfo) Column() Column {
+	return mysqlField{
+		tableName: c.TableName,
+		name:      c.Name,
+		length:    c.Length,
+		flags:     fieldFlag(c.Flags),
+		fieldType: c.Type,
+		decimals:  c.Decimals,
+		charSet:   c.CharSet,
+	}
+}
+
+func (f mysqlField) info() ColumnInfo {
+	return ColumnInfo{
+		TableName: f.tableName,
+		Name:      f.name,
+		Length:    f.length,
+		Flags:     uint16(f.flags),
+		Type:      f.fieldType,
+		Decimals:  f.decimals,
+		CharSet:   f.charSet,
+	}
+}
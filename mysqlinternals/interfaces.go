@@ -0,0 +1,149 @@
+// sqlinternals for github.com/go-sql-driver/mysql - retrieve column metadata from sql.*Row / sql.*Rows
+//
+// Copyright 2013 Arne Hormann. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mysqlinternals
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+
+	"github.com/arnehormann/sqlinternals"
+)
+
+// databaseType is the field type, flags and collation for a type name reported by DatabaseTypeName
+type databaseType struct {
+	fieldType byte
+	flags     fieldFlag
+	charSet   uint8
+}
+
+// collation assumed for TEXT types, the interfaces don't report it;
+// utf8mb4_general_ci may be aliased, so only the character set utf8mb4 is reported
+const textCharSet = 45
+
+// type names reported by github.com/go-sql-driver/mysql in ColumnTypes()[i].DatabaseTypeName()
+var databaseTypes = map[string]databaseType{
+	"TINYINT":    {fieldType: fieldTypeTiny},
+	"SMALLINT":   {fieldType: fieldTypeShort},
+	"MEDIUMINT":  {fieldType: fieldTypeInt24},
+	"INT":        {fieldType: fieldTypeLong},
+	"BIGINT":     {fieldType: fieldTypeLongLong},
+	"FLOAT":      {fieldType: fieldTypeFloat},
+	"DOUBLE":     {fieldType: fieldTypeDouble},
+	"DECIMAL":    {fieldType: fieldTypeNewDecimal},
+	"YEAR":       {fieldType: fieldTypeYear},
+	"DATE":       {fieldType: fieldTypeDate},
+	"TIME":       {fieldType: fieldTypeTime},
+	"TIMESTAMP":  {fieldType: fieldTypeTimestamp},
+	"DATETIME":   {fieldType: fieldTypeDateTime},
+	"NULL":       {fieldType: fieldTypeNULL},
+	"BIT":        {fieldType: fieldTypeBit},
+	"VARCHAR":    {fieldType: fieldTypeVarString},
+	"VARBINARY":  {fieldType: fieldTypeVarString, flags: flagBinary},
+	"CHAR":       {fieldType: fieldTypeString},
+	"BINARY":     {fieldType: fieldTypeString, flags: flagBinary},
+	"ENUM":       {fieldType: fieldTypeEnum},
+	"SET":        {fieldType: fieldTypeSet},
+	"TINYTEXT":   {fieldType: fieldTypeTinyBLOB, charSet: textCharSet},
+	"TINYBLOB":   {fieldType: fieldTypeTinyBLOB, flags: flagBinary},
+	"TEXT":       {fieldType: fieldTypeBLOB, charSet: textCharSet},
+	"BLOB":       {fieldType: fieldTypeBLOB, flags: flagBinary},
+	"MEDIUMTEXT": {fieldType: fieldTypeMediumBLOB, charSet: textCharSet},
+	"MEDIUMBLOB": {fieldType: fieldTypeMediumBLOB, flags: flagBinary},
+	"LONGTEXT":   {fieldType: fieldTypeLongBLOB, charSet: textCharSet},
+	"LONGBLOB":   {fieldType: fieldTypeLongBLOB, flags: flagBinary},
+	"GEOMETRY":   {fieldType: fieldTypeGeometry},
+	"JSON":       {fieldType: fieldTypeJSON},
+	"VECTOR":     {fieldType: fieldTypeVector},
+}
+
+// ColumnsViaDriverInterfaces retrieves a []Column for driver.Rows without unsafe casts
+// if they implement driver.RowsColumnTypeDatabaseTypeName.
+//
+// The optional driver.RowsColumnTypeNullable, driver.RowsColumnTypeLength and
+// driver.RowsColumnTypePrecisionScale are used when available.
+// The interfaces don't provide table names and key, ZEROFILL or AUTO_INCREMENT flags,
+// the columns report them as empty or unset.
+// They don't provide collations either; TEXT types are told from BLOB types by their
+// type name and report the character set utf8mb4 with an unknown collation,
+// all other columns report no collation.
+// It falls back to ColumnsFromDriverRows if the driver.Rows don't implement
+// driver.RowsColumnTypeDatabaseTypeName.
+func ColumnsViaDriverInterfaces(dr driver.Rows) ([]Column, error) {
+	const errUnknownType = mysqlError("ColumnsViaDriverInterfaces: unknown database type name")
+	if dr == nil {
+		return nil, mysqlError("ColumnsViaDriverInterfaces is not available")
+	}
+	typeNames, ok := dr.(driver.RowsColumnTypeDatabaseTypeName)
+	if !ok {
+		return ColumnsFromDriverRows(dr)
+	}
+	nullables, _ := dr.(driver.RowsColumnTypeNullable)
+	lengths, _ := dr.(driver.RowsColumnTypeLength)
+	precisionScales, _ := dr.(driver.RowsColumnTypePrecisionScale)
+	names := dr.Columns()
+	columns := make([]Column, len(names))
+	for i, name := range names {
+		typeName := typeNames.ColumnTypeDatabaseTypeName(i)
+		unsigned := strings.HasPrefix(typeName, "UNSIGNED ")
+		dbType, ok := databaseTypes[strings.TrimPrefix(typeName, "UNSIGNED ")]
+		if !ok {
+			return nil, errUnknownType
+		}
+		f := mysqlField{name: name, fieldType: dbType.fieldType, flags: dbType.flags, charSet: dbType.charSet}
+		if unsigned {
+			f.flags |= flagUnsigned
+		}
+		if nullables != nil {
+			if nullable, ok := nullables.ColumnTypeNullable(i); ok && !nullable {
+				f.flags |= flagNotNULL
+			}
+		}
+		if lengths != nil {
+			if length, ok := lengths.ColumnTypeLength(i); ok && length >= 0 && length <= 1<<32-1 {
+				f.length = uint32(length)
+			}
+		}
+		if precisionScales != nil {
+			if precision, scale, ok := precisionScales.ColumnTypePrecisionScale(i); ok && scale >= 0 && scale <= 0xff {
+				f.decimals = byte(scale)
+				if f.IsDecimal() && precision >= 0 {
+					// inverse of DecimalSize
+					f.length = uint32(precision) + 1
+					if scale > 0 {
+						f.length++
+					}
+				}
+			}
+		}
+		columns[i] = f
+	}
+	return columns, nil
+}
+
+// DriverScanType returns the type the driver reports for the column at index of sql.Rows or sql.Row
+// if its driver.Rows implement driver.RowsColumnTypeScanType.
+//
+// It matches what Scan expects exactly, the results differ from ReflectGoType:
+// github.com/go-sql-driver/mysql reports sql.NullInt64, sql.NullFloat64 and mysql.NullTime
+// for nullable columns and sql.RawBytes for DECIMAL, TIME, strings and BLOBs, see ScanType.
+func DriverScanType(rowOrRows interface{}, index int) (reflect.Type, bool) {
+	if rowOrRows == nil {
+		return nil, false
+	}
+	rows, err := sqlinternals.Inspect(rowOrRows)
+	if err != nil {
+		return nil, false
+	}
+	scanTypes, ok := rows.(driver.RowsColumnTypeScanType)
+	if !ok || index < 0 || index >= len(scanTypes.Columns()) {
+		return nil, false
+	}
+	return scanTypes.ColumnTypeScanType(index), true
+}
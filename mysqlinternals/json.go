@@ -0,0 +1,86 @@
+// sqlinternals for github.com/go-sql-driver/mysql - retrieve column metadata from sql.*Row / sql.*Rows
+//
+// Copyright 2013 Arne Hormann. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mysqlinternals
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+var (
+	typeJSONNumber = reflect.TypeOf(json.Number(""))
+	typeRawMessage = reflect.TypeOf(json.RawMessage{})
+	typeBool       = reflect.TypeOf(false)
+	typeInterface  = reflect.TypeOf((*interface{})(nil)).Elem()
+)
+
+// JavaScript numbers are doubles, larger integers lose precision
+const jsonMaxSafeBits = 53
+
+// retrieve a reflect.Type for the mysql field that encodes to JSON without losing data.
+func (f mysqlField) ReflectJSONType() reflect.Type {
+	t := f.jsonType()
+	if f.IsNotNull() {
+		return t
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Interface:
+		// nil already encodes as null
+		return t
+	}
+	return reflect.PtrTo(t)
+}
+
+func (f mysqlField) jsonType() reflect.Type {
+	switch f.fieldType {
+	case fieldTypeLongLong:
+		// 64 bit integers exceed the precision of JavaScript numbers
+		return typeString
+	case fieldTypeTiny, fieldTypeShort, fieldTypeInt24, fieldTypeLong:
+		t, _ := f.SmallestGoIntType()
+		return t
+	case fieldTypeFloat:
+		return typeFloat32
+	case fieldTypeDouble:
+		return typeFloat64
+	case fieldTypeDecimal, fieldTypeNewDecimal:
+		return typeJSONNumber
+	case fieldTypeDate, fieldTypeNewDate, fieldTypeTimestamp, fieldTypeDateTime:
+		// encoded as RFC 3339
+		return typeTime
+	case fieldTypeYear:
+		return typeUint16
+	case fieldTypeTime:
+		// may exceed a day or be negative
+		return typeString
+	case fieldTypeBit:
+		switch {
+		case f.length == 1:
+			return typeBool
+		case f.length <= jsonMaxSafeBits:
+			return typeUint64
+		}
+		return typeString
+	case fieldTypeVarChar, fieldTypeVarString, fieldTypeString, fieldTypeEnum:
+		return typeString
+	case fieldTypeSet:
+		return typeStrings
+	case fieldTypeJSON:
+		return typeRawMessage
+	case fieldTypeTinyBLOB, fieldTypeMediumBLOB, fieldTypeBLOB, fieldTypeLongBLOB:
+		if f.isTextBlob() {
+			return typeString
+		}
+		// encoded as base64
+		return typeBytes
+	case fieldTypeGeometry, fieldTypeVector:
+		return typeBytes
+	}
+	return typeInterface
+}
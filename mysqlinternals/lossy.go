@@ -0,0 +1,128 @@
+// sqlinternals for github.com/go-sql-driver/mysql - retrieve column metadata from sql.*Row / sql.*Rows
+//
+// Copyright 2013 Arne Hormann. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mysqlinternals
+
+import (
+	"reflect"
+)
+
+// nullableValueType returns the type of the value in sql.NullInt64 and similar types.
+func nullableValueType(t reflect.Type) (reflect.Type, bool) {
+	if t.Kind() != reflect.Struct || t.NumField() != 2 {
+		return nil, false
+	}
+	if valid, ok := t.FieldByName("Valid"); !ok || valid.Type.Kind() != reflect.Bool || valid.Index[0] != 1 {
+		return nil, false
+	}
+	return t.Field(0).Type, true
+}
+
+// mantissa bits of floating point types
+func mantissaBits(t reflect.Type) uintptr {
+	if t.Kind() == reflect.Float32 {
+		return 24
+	}
+	return 53
+}
+
+// check whether scanning into dst may lose data
+func (f mysqlField) LossyScanTo(dst reflect.Type) (bool, string) {
+	for dst.Kind() == reflect.Ptr {
+		dst = dst.Elem()
+	}
+	if t, ok := nullableValueType(dst); ok {
+		dst = t
+	}
+	switch {
+	case dst.Kind() == reflect.Interface && dst.NumMethod() == 0,
+		dst.Kind() == reflect.String,
+		dst.Kind() == reflect.Slice && dst.Elem().Kind() == reflect.Uint8:
+		// the raw values are kept
+		return false, ""
+	}
+	src, err := f.ReflectGoType()
+	if err != nil {
+		return true, err.Error()
+	}
+	switch {
+	case src == dst:
+		return false, ""
+	case f.IsInteger():
+		switch {
+		case fitsInto(src, dst):
+			return false, ""
+		case dst.Kind() == reflect.Float32 || dst.Kind() == reflect.Float64:
+			if src.Size()*8 <= mantissaBits(dst) {
+				return false, ""
+			}
+			return true, src.String() + " values exceed the precision of " + dst.String()
+		}
+		return true, src.String() + " values exceed the range of " + dst.String()
+	case f.IsFloatingPoint():
+		if fitsInto(src, dst) {
+			return false, ""
+		}
+		return true, src.String() + " values exceed the range or precision of " + dst.String()
+	case f.IsDecimal():
+		precision, scale, _ := f.DecimalSize()
+		switch dst.Kind() {
+		case reflect.Float32, reflect.Float64:
+			return true, "DECIMAL values are rounded to binary floating point in " + dst.String()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if scale > 0 {
+				return true, "the fractional part of DECIMAL values is lost in " + dst.String()
+			}
+			if !f.IsUnsigned() && dst.Kind() >= reflect.Uint {
+				return true, "negative DECIMAL values exceed the range of " + dst.String()
+			}
+			// log10(2) is about 0.3, n bits hold all numbers with n*3/10 digits
+			if precision <= int64(dst.Size()*8-1)*3/10 {
+				return false, ""
+			}
+			return true, "DECIMAL values exceed the range of " + dst.String()
+		}
+	case f.IsTime():
+		if f.Decimals() > 0 {
+			return true, "fractional seconds are lost in " + dst.String()
+		}
+	}
+	return true, dst.String() + " can't hold " + f.MysqlType() + " values"
+}
+
+// representative types of the kinds for AssignableToKind
+var kindTypes = map[reflect.Kind]reflect.Type{
+	reflect.Bool:      reflect.TypeOf(false),
+	reflect.Int:       reflect.TypeOf(int(0)),
+	reflect.Int8:      typeInt8,
+	reflect.Int16:     typeInt16,
+	reflect.Int32:     typeInt32,
+	reflect.Int64:     typeInt64,
+	reflect.Uint:      reflect.TypeOf(uint(0)),
+	reflect.Uint8:     typeUint8,
+	reflect.Uint16:    typeUint16,
+	reflect.Uint32:    typeUint32,
+	reflect.Uint64:    typeUint64,
+	reflect.Float32:   typeFloat32,
+	reflect.Float64:   typeFloat64,
+	reflect.String:    typeString,
+	reflect.Slice:     typeBytes,
+	reflect.Struct:    typeTime,
+	reflect.Interface: reflect.TypeOf((*interface{})(nil)).Elem(),
+}
+
+// check whether values can be scanned into variables of the kind without loss
+func (f mysqlField) AssignableToKind(k reflect.Kind) bool {
+	t, ok := kindTypes[k]
+	if !ok {
+		return false
+	}
+	lossy, _ := f.LossyScanTo(t)
+	return !lossy
+}
@@ -14,6 +14,10 @@ import (
 	"fmt"
 	"math/big"
 	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-sql-driver/mysql"
@@ -28,10 +32,22 @@ type Column interface {
 	// mysql.name
 
 	// Name returns the column name, matching that of a call to Columns() in database/sql
+	// It shares the memory of the driver's field without a copy, so lookups in a map[string]
+	// keyed by column names don't allocate.
 	Name() string
+	// OrdinalPosition returns the position of the column in its table, starting at 1.
+	// The MySQL protocol does not transmit it, ok is false until a driver provides it.
+	OrdinalPosition() (position int, ok bool)
+
+	// mysql.tableName
+
+	// TableName returns the name or alias of the table containing the column, it is empty for expressions
+	TableName() string
 
 	// derived from mysqlField.fieldType
 
+	// Category returns the kind of data contained in the column.
+	Category() TypeCategory
 	// MysqlType returns the raw sql type name without parameters and modifiers
 	MysqlType() string
 	// IsNumber returns true if the column contains numbers (one of integer, decimal or floating point)
@@ -42,12 +58,32 @@ type Column interface {
 	IsFloatingPoint() bool
 	// IsDecimal returns true if the column contains decimal numbers
 	IsDecimal() bool
-	// IsText returns true if the column contains textual data
+	// IsText returns true if the column contains textual data.
+	// TEXT types share the type codes of BLOB types and are told apart by their collation;
+	// without a known collation, they are reported as BLOB types.
 	IsText() bool
 	// IsBlob returns true if the column contains binary blobs
 	IsBlob() bool
 	// IsTime returns true if the column contains temporal data
 	IsTime() bool
+	// IsTimestamp returns true if the column is a TIMESTAMP.
+	// TIMESTAMP values are stored in UTC and converted to the time zone of the session,
+	// DATETIME values are not converted. Set the DSN parameters parseTime and loc
+	// of the driver to retrieve them as time.Time in the matching location.
+	IsTimestamp() bool
+	// IsVector returns true if the column contains vectors (MySQL 9)
+	IsVector() bool
+	// IsNull returns true if the column has the type NULL, e.g. for SELECT NULL
+	IsNull() bool
+	// GeometryType returns the spatial type of GEOMETRY columns.
+	// The protocol sends POINT, POLYGON etc. as GEOMETRY without a subtype,
+	// so it returns ("GEOMETRY", false) for all spatial columns and ("", false) for others.
+	GeometryType() (name string, ok bool)
+	// ExtendedTypeName returns the name MariaDB sends in its extended metadata for types
+	// implemented atop a base type, e.g. INET6 or UUID.
+	// github.com/go-sql-driver/mysql doesn't request the extended metadata,
+	// so it always returns ("", false) and these columns report their base type.
+	ExtendedTypeName() (name string, ok bool)
 
 	// derived from mysqlField.flags
 	// TODO: not quite sure about these, add tests and check them.
@@ -58,36 +94,150 @@ type Column interface {
 	IsUniqueKey() bool
 	// IsMultipleKey returns true if the column is marked as part of a regular key (*).
 	IsMultipleKey() bool
+	// IsKeyPart returns true if the column is marked as part of any key (*).
+	IsKeyPart() bool
+	// KeyKind returns the strongest key role the column is marked with (*).
+	// MySQL may set several key flags at once, KeyPrimary takes precedence
+	// over KeyUnique, which takes precedence over KeyMultiple.
+	KeyKind() KeyKind
 	// IsNotNull returns true if the column is marked as NOT NULL (*).
 	IsNotNull() bool
 	// IsUnsigned returns true if the column is marked as UNSIGNED (*).
 	IsUnsigned() bool
+	// IsSigned returns true if the column is numeric and not marked as UNSIGNED (*).
+	IsSigned() bool
 	// IsZerofill returns true if the column is marked as ZEROFILL (*).
 	IsZerofill() bool
 	// IsBinary returns true if the column is marked as BINARY (*).
 	IsBinary() bool
 	// IsAutoIncrement returns true if the column is marked as AUTO_INCREMENT (*).
+	// Both protocols send the same column definitions, so the flag does not depend
+	// on the protocol. MySQL only sets it for columns read directly from a table;
+	// use ColumnsWithSchema to read it from information_schema.
 	IsAutoIncrement() bool
 
 	// derived from mysqlField.decimals
 	Decimals() int
 
+	// derived from mysqlField.charSet
+
+	// Collation returns the name of the collation of the column, ok is false for unknown ids.
+	// The driver only keeps the lower byte of the collation id, so MySQL 8.0's ids 256 to 323
+	// are reported as 0 to 67, e.g. utf8mb4_0900_as_cs (278) as koi8u_general_ci (22).
+	// ok is false for all ids up to 67 because of that. MariaDB's ids above 255 can alias
+	// any id; this can't be detected from the column.
+	Collation() (name string, ok bool)
+	// IsCaseSensitive reports whether comparisons of textual values are case sensitive,
+	// known is false for non-textual columns and unknown collations.
+	// Columns with the BINARY flag, binary strings and _bin collations, are case sensitive.
+	IsCaseSensitive() (sensitive bool, known bool)
+
+	// derived from mysqlField.length and mysqlField.decimals
+
+	// DecimalSize returns precision and scale of DECIMAL columns, ok is false for other types.
+	DecimalSize() (precision int64, scale int64, ok bool)
+	// MaxLength returns the maximum length in bytes of textual and blob columns, ok is false for other types.
+	MaxLength() (length int64, ok bool)
+	// CharLength returns the maximum length in characters of textual columns, e.g. 10 for VARCHAR(10).
+	// The length in bytes is divided by the maximum bytes per character of the character set.
+	// ok is false for other columns and unknown character sets; the character set of the
+	// ids aliased by Collation is only known for utf8mb4.
+	CharLength() (length int64, ok bool)
+
 	// derived from mysqlField.fieldType and mysqlField.flags
 
 	// MysqlParameters returns the category of parameters the SQL type expects in MysqlDeclaration.
 	MysqlParameters() parameterType
 	// MysqlDeclaration returns a type declaration usable in a CREATE TABLE statement.
 	MysqlDeclaration(params ...interface{}) (string, error)
+	// MysqlDeclarationWithCharset works like MysqlDeclaration, but adds the character set
+	// and collation to CHAR, VARCHAR, TEXT, ENUM and SET columns with a known, non-binary character set.
+	// The collation is omitted if only the character set is known, see Collation.
+	// The deprecated utf8 is written as utf8mb3 to be unambiguous.
+	MysqlDeclarationWithCharset(params ...interface{}) (string, error)
+	// MysqlTypeOnly returns the type with its parameters like MysqlDeclaration, but without
+	// the attributes UNSIGNED, ZEROFILL, BINARY and NOT NULL, e.g. for type comparisons.
+	MysqlTypeOnly(params ...interface{}) (string, error)
+	// MysqlDeclarationFor works like MysqlDeclaration in DeclarationLegacy mode.
+	// In DeclarationModern mode, it omits ZEROFILL, which is deprecated since MySQL 8.0.17.
+	// Integer display widths are deprecated, too; they are ignored in both modes.
+	MysqlDeclarationFor(mode DeclarationMode, params ...interface{}) (string, error)
+	// SmallestGoIntType returns the smallest Go integer type able to represent all values
+	// of an integer column, taking IsUnsigned() into account.
+	// It returns an error for non-integer columns.
+	SmallestGoIntType() (reflect.Type, error)
 	// ReflectGoType returns the smallest Go type able to represent all possible regular values.
 	// The returned types assume a non-NULL value and may cause problems
 	// on conversion (e.g. MySQL DATE "0000-00-00", which is not mappable to Go).
 	ReflectGoType() (reflect.Type, error)
+	// ReflectGoTypeOrBytes works like ReflectGoType, but returns []byte instead of an error
+	// for types without a matching Go type; the driver delivers their values as raw bytes.
+	ReflectGoTypeOrBytes() reflect.Type
 	// ReflectSqlType returns a Go type able to contain the SQL type, including null values.
 	// The returned types may cause problems on conversion
 	// (e.g. MySQL DATE "0000-00-00", which is not mappable to Go).
 	// The returned type assumes IsNotNull() to be false when forceNullable is set
 	// and attempts to return a nullable type (e.g. sql.NullString instead of string).
 	ReflectSqlType(forceNullable bool) (reflect.Type, error)
+	// ReflectJSONType returns a Go type whose values encode to JSON without losing data.
+	// BIGINT becomes string as it exceeds the precision of JavaScript numbers,
+	// DECIMAL becomes json.Number, DATE, DATETIME and TIMESTAMP become time.Time (RFC 3339),
+	// TIME becomes string, BIT(1) bool and longer BIT columns uint64 up to 53 bits or string.
+	// JSON columns become json.RawMessage and binary types []byte (base64).
+	// Nullable columns return a pointer unless the type is a slice or interface{}.
+	ReflectJSONType() reflect.Type
+	// ConvertValue converts a driver.Value of the column to the type of ReflectGoType
+	// where the driver delivers a different representation.
+	// The comma separated members of SET values are split into a []string,
+	// the empty SET becomes an empty slice. Other values, including nil, are returned as they are.
+	ConvertValue(src interface{}) (interface{}, error)
+	// LossyScanTo reports whether scanning values of the column into dst may lose data
+	// and describes the reason. Pointers and nullable types like sql.NullInt64 are unwrapped;
+	// strings, []byte and interface{} keep the raw values and are never lossy.
+	LossyScanTo(dst reflect.Type) (lossy bool, reason string)
+	// AssignableToKind reports whether values of the column can be scanned into variables
+	// of the kind without loss, based on LossyScanTo. Slice stands for []byte and Struct for time.Time,
+	// pass the element kind for pointers. NULL needs a pointer or a nullable type regardless of the kind.
+	AssignableToKind(k reflect.Kind) bool
+	// IsGenerated looks up whether the column is a generated column in information_schema
+	// and returns its generation expression.
+	// The table is searched in the current database of conn, it returns an error if conn is nil.
+	// The driver only keeps the names used in the query: neither the table nor the column
+	// may be aliased and the table must be in the current database, otherwise the lookup
+	// misses or silently finds another column with the same names.
+	// Columns without a table return false and no error.
+	IsGenerated(conn *sql.Conn) (generated bool, expression string, err error)
+	// ColumnDefault looks up the default value of the column in COLUMN_DEFAULT of information_schema,
+	// e.g. "CURRENT_TIMESTAMP". Like for IsGenerated, the column must not be aliased.
+	// Columns without a table or a default return ("", false, nil).
+	// It is named ColumnDefault because SchemaColumn.DefaultValue reports the value
+	// already read by ColumnsWithSchema.
+	ColumnDefault(conn *sql.Conn) (value string, hasDefault bool, err error)
+	// SRID looks up the spatial reference system id of a GEOMETRY column in information_schema
+	// (SRS_ID, MySQL 8), the protocol doesn't send it. Like for IsGenerated, the column must not be aliased.
+	// ok is false for other columns, columns without a table and columns without an SRID.
+	SRID(conn *sql.Conn) (srid uint32, ok bool, err error)
+	// MysqlDeclarationWithSRID works like MysqlDeclaration, but appends the attribute "SRID n"
+	// for GEOMETRY columns with an SRID, e.g. "GEOMETRY NOT NULL SRID 4326".
+	MysqlDeclarationWithSRID(conn *sql.Conn, params ...interface{}) (string, error)
+	// ScanType returns the type github.com/go-sql-driver/mysql reports in ColumnTypes()[i].ScanType().
+	// The driver reports the same type for the text and the binary protocol;
+	// the values it returns for the text protocol are []byte and converted by database/sql on Scan.
+	// Like the driver, it returns the type of *interface{} for types unknown to it, e.g. VECTOR.
+	ScanType() reflect.Type
+	// DriverValueKind returns the kind of the driver.Value github.com/go-sql-driver/mysql emits
+	// for non-NULL values of the column with the protocol and the parseTime setting of the DSN.
+	// time.Time is reported as reflect.Struct and []byte as reflect.Slice.
+	// The binary protocol emits []byte for BIGINT UNSIGNED values exceeding math.MaxInt64.
+	// It returns reflect.Invalid for NULL columns and unknown types.
+	DriverValueKind(binary, parseTime bool) reflect.Kind
+	// WireEncoding returns how non-NULL values of the column are encoded in the packets
+	// of the text or the binary protocol.
+	WireEncoding(binary bool) WireEncoding
+
+	// Validate returns a description for each inconsistency detected in the metadata.
+	// It returns nil if the metadata looks sane.
+	Validate() []string
 }
 
 var _ Column = mysqlField{}
@@ -97,6 +247,16 @@ func (f mysqlField) Name() string {
 	return f.name
 }
 
+// name of the table
+func (f mysqlField) TableName() string {
+	return f.tableName
+}
+
+// position of the column in its table, unknown for github.com/go-sql-driver/mysql
+func (f mysqlField) OrdinalPosition() (int, bool) {
+	return 0, false
+}
+
 // is a numeric type
 func (f mysqlField) IsNumber() bool {
 	return f.IsInteger() || f.IsFloatingPoint() || f.IsDecimal()
@@ -104,61 +264,127 @@ func (f mysqlField) IsNumber() bool {
 
 // is a numeric integer type
 func (f mysqlField) IsInteger() bool {
-	switch f.fieldType {
-	case fieldTypeTiny, fieldTypeShort, fieldTypeInt24, fieldTypeLong, fieldTypeLongLong:
-		return true
-	}
-	return false
+	return f.Category() == CategoryInteger
 }
 
 // is a numeric binary floating point type
 func (f mysqlField) IsFloatingPoint() bool {
-	switch f.fieldType {
-	case fieldTypeFloat, fieldTypeDouble:
-		return true
-	}
-	return false
+	return f.Category() == CategoryFloat
 }
 
 // is a numeric decimal type
 func (f mysqlField) IsDecimal() bool {
-	switch f.fieldType {
-	case fieldTypeDecimal, fieldTypeNewDecimal:
-		return true
-	}
-	return false
+	return f.Category() == CategoryDecimal
 }
 
 // is a blob type
 func (f mysqlField) IsBlob() bool {
-	switch f.fieldType {
-	case fieldTypeTinyBLOB, fieldTypeMediumBLOB, fieldTypeBLOB, fieldTypeLongBLOB:
-		return true
-	}
-	return false
+	return f.Category() == CategoryBlob
 }
 
 // is a textual type
 func (f mysqlField) IsText() bool {
-	switch f.fieldType {
-	case fieldTypeVarChar, fieldTypeVarString, fieldTypeString:
-		return true
-	}
-	return false
+	return f.Category() == CategoryText
 }
 
 // is a temporal type
 func (f mysqlField) IsTime() bool {
-	switch f.fieldType {
-	case fieldTypeYear, fieldTypeDate, fieldTypeNewDate, fieldTypeTime, fieldTypeTimestamp, fieldTypeDateTime:
-		return true
+	return f.Category() == CategoryTemporal
+}
+
+// is a TIMESTAMP, the only temporal type with time zone conversion
+func (f mysqlField) IsTimestamp() bool {
+	return f.fieldType == fieldTypeTimestamp
+}
+
+// is a vector type
+func (f mysqlField) IsVector() bool {
+	return f.Category() == CategoryVector
+}
+
+// is the NULL type
+func (f mysqlField) IsNull() bool {
+	return f.Category() == CategoryNull
+}
+
+// MariaDB's extended type name, the driver doesn't request it
+func (f mysqlField) ExtendedTypeName() (string, bool) {
+	return "", false
+}
+
+// spatial subtype, it is not available in the protocol
+func (f mysqlField) GeometryType() (string, bool) {
+	if f.Category() != CategoryGeometry {
+		return "", false
+	}
+	return "GEOMETRY", false
+}
+
+// category of the field type, registered types take precedence
+func (f mysqlField) Category() TypeCategory {
+	if t, ok := registeredFieldType(f.fieldType); ok {
+		return t.category
+	}
+	if f.isBuiltinTextBlob() {
+		return CategoryText
 	}
-	return false
+	return categoryFor(f.fieldType)
 }
 
 // type name in MySQL (includes "NULL", which may not be used in table definitions)
 func (f mysqlField) MysqlType() string {
-	return mysqlNameFor(f.fieldType)
+	name := f.builtinType()
+	if override := typeNameOverride(f.fieldType); override != nil {
+		return override(f.info(), name)
+	}
+	return name
+}
+
+// type name without overrides set by SetTypeNameOverride
+func (f mysqlField) builtinType() string {
+	if t, ok := registeredFieldType(f.fieldType); ok {
+		return t.name
+	}
+	if f.isBuiltinTextBlob() {
+		return textBlobNames[f.fieldType]
+	}
+	return builtinNameFor(f.fieldType)
+}
+
+// builtinTypeOf returns the MysqlType of col without overrides set by SetTypeNameOverride,
+// the package compares these names internally.
+func builtinTypeOf(col Column) string {
+	if f, ok := col.(interface{ builtinType() string }); ok {
+		return f.builtinType()
+	}
+	return col.MysqlType()
+}
+
+// id of the binary collation, used by the BLOB types
+const binaryCharSet = 63
+
+// TEXT types use the type codes of BLOB types, but a collation other than binary
+var textBlobNames = map[byte]string{
+	fieldTypeTinyBLOB:   "TINYTEXT",
+	fieldTypeMediumBLOB: "MEDIUMTEXT",
+	fieldTypeBLOB:       "TEXT",
+	fieldTypeLongBLOB:   "LONGTEXT",
+}
+
+// is a TEXT type; the collation must be known, BLOB types are assumed otherwise
+func (f mysqlField) isTextBlob() bool {
+	if _, ok := registeredFieldType(f.fieldType); ok {
+		return false
+	}
+	return f.isBuiltinTextBlob()
+}
+
+// isTextBlob without checking the registered field types
+func (f mysqlField) isBuiltinTextBlob() bool {
+	if _, ok := textBlobNames[f.fieldType]; !ok {
+		return false
+	}
+	return f.charSet != 0 && f.charSet != binaryCharSet
 }
 
 // is part of the primary key
@@ -176,6 +402,24 @@ func (f mysqlField) IsMultipleKey() bool {
 	return f.flags&flagMultipleKey == flagMultipleKey
 }
 
+// is part of any key
+func (f mysqlField) IsKeyPart() bool {
+	return f.flags&(flagPriKey|flagUniqueKey|flagMultipleKey) != 0
+}
+
+// strongest key role of the field
+func (f mysqlField) KeyKind() KeyKind {
+	switch {
+	case f.IsPrimaryKey():
+		return KeyPrimary
+	case f.IsUniqueKey():
+		return KeyUnique
+	case f.IsMultipleKey():
+		return KeyMultiple
+	}
+	return KeyNone
+}
+
 // has NOT NULL attribute set
 func (f mysqlField) IsNotNull() bool {
 	return f.flags&flagNotNULL == flagNotNULL
@@ -186,6 +430,11 @@ func (f mysqlField) IsUnsigned() bool {
 	return f.flags&flagUnsigned == flagUnsigned
 }
 
+// is a numeric type without UNSIGNED attribute
+func (f mysqlField) IsSigned() bool {
+	return f.IsNumber() && !f.IsUnsigned()
+}
+
 // has ZEROFILL attribute set
 func (f mysqlField) IsZerofill() bool {
 	return f.flags&flagZeroFill == flagZeroFill
@@ -205,6 +454,58 @@ func (f mysqlField) Decimals() int {
 	return int(f.decimals)
 }
 
+// maximum length in bytes of textual and blob types
+func (f mysqlField) MaxLength() (int64, bool) {
+	if !f.IsText() && !f.IsBlob() {
+		return 0, false
+	}
+	return int64(f.length), true
+}
+
+// precision and scale of decimal types
+func (f mysqlField) DecimalSize() (int64, int64, bool) {
+	if !f.IsDecimal() {
+		return 0, 0, false
+	}
+	// the length includes the decimal point and the sign slot, even for UNSIGNED
+	precision, scale := int64(f.length)-1, int64(f.decimals)
+	if scale > 0 {
+		precision--
+	}
+	return precision, scale, true
+}
+
+// list contradictions in the metadata.
+// The BINARY flag is not checked, MySQL sets it for all types using the binary charset.
+func (f mysqlField) Validate() []string {
+	var problems []string
+	name := f.MysqlType()
+	if name == "" {
+		problems = append(problems, "unknown type code "+strconv.Itoa(int(f.fieldType)))
+	}
+	if !f.IsNumber() {
+		if f.IsUnsigned() {
+			problems = append(problems, "UNSIGNED is set on non-numeric type "+name)
+		}
+		if f.IsZerofill() {
+			problems = append(problems, "ZEROFILL is set on non-numeric type "+name)
+		}
+		if f.IsAutoIncrement() {
+			problems = append(problems, "AUTO_INCREMENT is set on non-numeric type "+name)
+		}
+	}
+	if f.IsZerofill() && !f.IsUnsigned() {
+		problems = append(problems, "ZEROFILL is set without UNSIGNED")
+	}
+	if f.IsInteger() && f.decimals != 0 {
+		problems = append(problems, "decimals are set on integer type "+name)
+	}
+	if f.IsPrimaryKey() && !f.IsNotNull() {
+		problems = append(problems, "PRIMARY KEY is set without NOT NULL")
+	}
+	return problems
+}
+
 const ( // base for reflection
 	reflect_uint8   = uint8(0)
 	reflect_uint16  = uint16(0)
@@ -238,22 +539,30 @@ var ( // reflect.Types
 	typeBigint  = reflect.TypeOf(big.NewInt(0))
 	typeBools   = reflect.TypeOf([]bool{})
 	typeBytes   = reflect.TypeOf([]byte{})
+	typeStrings = reflect.TypeOf([]string{})
 	typeTime    = reflect.TypeOf(time.Time{})
 	// nullable types
 	typeNullInt64   = reflect.TypeOf(sql.NullInt64{})
 	typeNullFloat64 = reflect.TypeOf(sql.NullFloat64{})
 	typeNullString  = reflect.TypeOf(sql.NullString{})
 	typeNullTime    = reflect.TypeOf(mysql.NullTime{})
+	// scan types
+	typeRawBytes = reflect.TypeOf(sql.RawBytes{})
+	typeUnknown  = reflect.TypeOf(new(interface{}))
 	// typeNullBool doesn't match in MySQL, boolean is (unsigned?) tinyint(1),
 	// it may have more than 2 states
 	//typeNullBool = reflect.TypeOf(sql.NullBool{})
 )
 
-// retrieve the best matching reflect.Type for the mysql field.
-// Returns an error if no matching type exists.
-func (f mysqlField) ReflectGoType() (reflect.Type, error) {
+// retrieve the smallest integer reflect.Type for the mysql field.
+// Returns an error if the field is not an integer type.
+func (f mysqlField) SmallestGoIntType() (reflect.Type, error) {
+	b, ok := f.asBuiltin()
+	if !ok {
+		return nil, errorTypeMismatch(f.fieldType)
+	}
 	if f.IsUnsigned() {
-		switch f.fieldType {
+		switch b.fieldType {
 		case fieldTypeTiny:
 			return typeUint8, nil
 		case fieldTypeShort:
@@ -263,9 +572,8 @@ func (f mysqlField) ReflectGoType() (reflect.Type, error) {
 		case fieldTypeLongLong:
 			return typeUint64, nil
 		}
-		// unsigned non-integer types fall through
 	}
-	switch f.fieldType {
+	switch b.fieldType {
 	case fieldTypeTiny:
 		return typeInt8, nil
 	case fieldTypeShort:
@@ -274,6 +582,23 @@ func (f mysqlField) ReflectGoType() (reflect.Type, error) {
 		return typeInt32, nil
 	case fieldTypeLongLong:
 		return typeInt64, nil
+	}
+	return nil, errorTypeMismatch(f.fieldType)
+}
+
+// retrieve the best matching reflect.Type for the mysql field.
+// Returns an error if no matching type exists.
+func (f mysqlField) ReflectGoType() (reflect.Type, error) {
+	if f.IsInteger() {
+		return f.SmallestGoIntType()
+	}
+	b, ok := f.asBuiltin()
+	if !ok {
+		return nil, errorTypeMismatch(f.fieldType)
+	}
+	// Go has no unsigned floating point or decimal types,
+	// UNSIGNED FLOAT, DOUBLE and DECIMAL use the signed ones
+	switch b.fieldType {
 	case fieldTypeFloat:
 		return typeFloat32, nil
 	case fieldTypeDouble:
@@ -286,15 +611,31 @@ func (f mysqlField) ReflectGoType() (reflect.Type, error) {
 		return typeBools, nil
 	case fieldTypeVarChar, fieldTypeVarString, fieldTypeString:
 		return typeString, nil
-	case fieldTypeTinyBLOB, fieldTypeMediumBLOB, fieldTypeBLOB, fieldTypeLongBLOB,
-		fieldTypeJSON:
+	case fieldTypeTinyBLOB, fieldTypeMediumBLOB, fieldTypeBLOB, fieldTypeLongBLOB:
+		if b.isTextBlob() {
+			return typeString, nil
+		}
+		return typeBytes, nil
+	case fieldTypeJSON, fieldTypeVector:
+		// VECTOR is sent as an array of little endian float32 values
 		return typeBytes, nil
-	case fieldTypeEnum, fieldTypeSet, fieldTypeGeometry, fieldTypeNULL:
+	case fieldTypeSet:
+		// the selected members, see ConvertValue
+		return typeStrings, nil
+	case fieldTypeEnum, fieldTypeGeometry, fieldTypeNULL:
 		return nil, errorTypeMismatch(f.fieldType)
 	}
 	return nil, errors.New("unknown mysql type")
 }
 
+// retrieve the best matching reflect.Type for the mysql field or []byte if there is none.
+func (f mysqlField) ReflectGoTypeOrBytes() reflect.Type {
+	if t, err := f.ReflectGoType(); err == nil {
+		return t
+	}
+	return typeBytes
+}
+
 // retrieve the best matching reflect.Type for the mysql field.
 // Returns an error if no matching type exists.
 func (f mysqlField) ReflectSqlType(forceNullable bool) (reflect.Type, error) {
@@ -304,12 +645,17 @@ func (f mysqlField) ReflectSqlType(forceNullable bool) (reflect.Type, error) {
 			return typeNullInt64, nil
 		case f.IsFloatingPoint():
 			return typeNullFloat64, nil
+		case f.IsDecimal():
+			// a string keeps arbitrary precision without loss
+			return typeNullString, nil
 		case f.IsText():
 			return typeNullString, nil
 		case f.IsTime():
 			return typeNullTime, nil
-		case f.IsBlob():
+		case f.IsBlob(), f.fieldType == fieldTypeBit:
 			return typeBytes, nil // []byte can be nil on its own
+		case f.fieldType == fieldTypeSet:
+			return typeStrings, nil // []string can be nil on its own
 		}
 		// All other types are not nullable in Go right now
 		return nil, errorTypeMismatch(f.fieldType)
@@ -317,6 +663,116 @@ func (f mysqlField) ReflectSqlType(forceNullable bool) (reflect.Type, error) {
 	return f.ReflectGoType()
 }
 
+// retrieve the scan type the driver uses for the mysql field.
+func (f mysqlField) ScanType() reflect.Type {
+	switch f.fieldType {
+	case fieldTypeTiny, fieldTypeShort, fieldTypeYear, fieldTypeInt24, fieldTypeLong, fieldTypeLongLong:
+		if !f.IsNotNull() {
+			return typeNullInt64
+		}
+		if f.fieldType == fieldTypeYear {
+			// the driver handles YEAR like SMALLINT
+			f.fieldType = fieldTypeShort
+		}
+		t, _ := f.SmallestGoIntType()
+		return t
+	case fieldTypeFloat, fieldTypeDouble:
+		if !f.IsNotNull() {
+			return typeNullFloat64
+		}
+		if f.fieldType == fieldTypeFloat {
+			return typeFloat32
+		}
+		return typeFloat64
+	case fieldTypeDecimal, fieldTypeNewDecimal, fieldTypeVarChar, fieldTypeBit,
+		fieldTypeEnum, fieldTypeSet, fieldTypeTinyBLOB, fieldTypeMediumBLOB, fieldTypeLongBLOB,
+		fieldTypeBLOB, fieldTypeVarString, fieldTypeString, fieldTypeGeometry, fieldTypeJSON,
+		fieldTypeTime:
+		return typeRawBytes
+	case fieldTypeDate, fieldTypeNewDate, fieldTypeTimestamp, fieldTypeDateTime:
+		// the driver always uses NullTime, it handles parseTime regardless of nullability
+		return typeNullTime
+	}
+	// the driver has no scan type for VECTOR and other unknown types
+	return typeUnknown
+}
+
+// isUnknownLengthEncoded reports whether the server sends values of the field as length-encoded
+// strings although the driver has no ScanType for it; the driver reads them as []byte with the
+// text protocol and fails to read them with the binary protocol.
+func (f mysqlField) isUnknownLengthEncoded() bool {
+	return f.fieldType == fieldTypeVector
+}
+
+// retrieve the kind of the driver.Value the driver emits for the mysql field.
+func (f mysqlField) DriverValueKind(binary, parseTime bool) reflect.Kind {
+	switch f.fieldType {
+	case fieldTypeNULL:
+		return reflect.Invalid
+	case fieldTypeDate, fieldTypeNewDate, fieldTypeTimestamp, fieldTypeDateTime:
+		if parseTime {
+			return reflect.Struct
+		}
+		return reflect.Slice
+	}
+	if !binary {
+		// the text protocol sends everything else as strings
+		if f.ScanType() == typeUnknown && !f.isUnknownLengthEncoded() {
+			return reflect.Invalid
+		}
+		return reflect.Slice
+	}
+	switch f.fieldType {
+	case fieldTypeTiny, fieldTypeShort, fieldTypeYear, fieldTypeInt24, fieldTypeLong, fieldTypeLongLong:
+		return reflect.Int64
+	case fieldTypeFloat:
+		return reflect.Float32
+	case fieldTypeDouble:
+		return reflect.Float64
+	}
+	if f.ScanType() == typeRawBytes {
+		return reflect.Slice
+	}
+	return reflect.Invalid
+}
+
+// WireEncoding is the encoding of values in result set packets.
+type WireEncoding int
+
+const (
+	// unknown type
+	WireUnknown WireEncoding = iota
+	// no value is sent, the column type is NULL
+	WireNone
+	// length-encoded string, the text protocol sends all values this way
+	WireLengthEncoded
+	// little endian integer or IEEE 754 floating point number of the binary protocol,
+	// the width of the MySQL type: 1 byte for TINYINT to 8 bytes for BIGINT and DOUBLE
+	WireFixedWidth
+	// length-encoded date, datetime or time structure of the binary protocol
+	WireTemporal
+)
+
+// retrieve the encoding of the mysql field in result set packets.
+func (f mysqlField) WireEncoding(binary bool) WireEncoding {
+	switch {
+	case f.fieldType == fieldTypeNULL:
+		return WireNone
+	case f.ScanType() == typeUnknown && !f.isUnknownLengthEncoded():
+		return WireUnknown
+	case !binary:
+		return WireLengthEncoded
+	}
+	switch f.fieldType {
+	case fieldTypeTiny, fieldTypeShort, fieldTypeYear, fieldTypeInt24, fieldTypeLong, fieldTypeLongLong,
+		fieldTypeFloat, fieldTypeDouble:
+		return WireFixedWidth
+	case fieldTypeDate, fieldTypeNewDate, fieldTypeTimestamp, fieldTypeDateTime, fieldTypeTime:
+		return WireTemporal
+	}
+	return WireLengthEncoded
+}
+
 type errorTypeMismatch uint8
 
 func (e errorTypeMismatch) Error() string {
@@ -324,6 +780,14 @@ func (e errorTypeMismatch) Error() string {
 }
 
 func mysqlNameFor(fieldType uint8) string {
+	if t, ok := registeredFieldType(fieldType); ok {
+		return t.name
+	}
+	return builtinNameFor(fieldType)
+}
+
+// builtinNameFor returns the name of the field type without checking the registered ones.
+func builtinNameFor(fieldType uint8) string {
 	switch fieldType {
 	// --- integer ---
 	case fieldTypeTiny:
@@ -385,10 +849,238 @@ func mysqlNameFor(fieldType uint8) string {
 	// --- JSON ---
 	case fieldTypeJSON:
 		return "JSON"
+	// --- vector ---
+	case fieldTypeVector:
+		return "VECTOR"
 	}
 	return ""
 }
 
+// TypeCategory is the kind of data contained in a column.
+type TypeCategory int
+
+const (
+	// unknown or unsupported type
+	CategoryUnknown TypeCategory = iota
+	// TINYINT, SMALLINT, MEDIUMINT, INT and BIGINT
+	CategoryInteger
+	// FLOAT and DOUBLE
+	CategoryFloat
+	// DECIMAL and NUMERIC
+	CategoryDecimal
+	// CHAR and VARCHAR
+	CategoryText
+	// the BLOB types
+	CategoryBlob
+	// YEAR, DATE, TIME, TIMESTAMP and DATETIME
+	CategoryTemporal
+	// ENUM
+	CategoryEnum
+	// SET
+	CategorySet
+	// GEOMETRY
+	CategoryGeometry
+	// JSON
+	CategoryJSON
+	// NULL
+	CategoryNull
+	// BIT
+	CategoryBit
+	// VECTOR
+	CategoryVector
+)
+
+// KeyKind is the role of a column in the keys of its table.
+type KeyKind int
+
+const (
+	// not part of a key
+	KeyNone KeyKind = iota
+	// part of the primary key
+	KeyPrimary
+	// part of a unique key
+	KeyUnique
+	// part of a nonunique key
+	KeyMultiple
+)
+
+func categoryFor(fieldType uint8) TypeCategory {
+	switch fieldType {
+	case fieldTypeTiny, fieldTypeShort, fieldTypeInt24, fieldTypeLong, fieldTypeLongLong:
+		return CategoryInteger
+	case fieldTypeFloat, fieldTypeDouble:
+		return CategoryFloat
+	case fieldTypeDecimal, fieldTypeNewDecimal:
+		return CategoryDecimal
+	case fieldTypeVarChar, fieldTypeVarString, fieldTypeString:
+		return CategoryText
+	case fieldTypeTinyBLOB, fieldTypeMediumBLOB, fieldTypeBLOB, fieldTypeLongBLOB:
+		return CategoryBlob
+	case fieldTypeYear, fieldTypeDate, fieldTypeNewDate, fieldTypeTime, fieldTypeTimestamp, fieldTypeDateTime:
+		return CategoryTemporal
+	case fieldTypeEnum:
+		return CategoryEnum
+	case fieldTypeSet:
+		return CategorySet
+	case fieldTypeGeometry:
+		return CategoryGeometry
+	case fieldTypeJSON:
+		return CategoryJSON
+	case fieldTypeNULL:
+		return CategoryNull
+	case fieldTypeBit:
+		return CategoryBit
+	case fieldTypeVector:
+		return CategoryVector
+	}
+	return CategoryUnknown
+}
+
+type fieldTypeInfo struct {
+	name     string
+	category TypeCategory
+}
+
+var (
+	// serializes the changes of fieldTypeRegistry and typeNameOverrides
+	fieldTypeMutex sync.Mutex
+	// map[uint8]fieldTypeInfo of the field types registered with RegisterFieldType,
+	// replaced by a copy on changes and read without locking
+	fieldTypeRegistry atomic.Value
+)
+
+// RegisterFieldType sets the name and category for a MySQL field type code.
+// An empty name removes the registration.
+//
+// The type codes are copied from github.com/go-sql-driver/mysql and may differ between
+// driver versions. RegisterFieldType corrects or extends them, registered codes take precedence
+// over the builtin ones in MysqlType, the category predicates (IsInteger etc.), SmallestGoIntType,
+// ReflectGoType and the declaration methods.
+// A code registered with a category other than its builtin one maps to the Go type of the
+// widest builtin type of the category, e.g. int64 for CategoryInteger, and is declared by name
+// without parameters.
+func RegisterFieldType(code byte, name string, category TypeCategory) {
+	fieldTypeMutex.Lock()
+	types := copyFieldTypes(registeredFieldTypes())
+	if name == "" {
+		delete(types, code)
+	} else {
+		types[code] = fieldTypeInfo{name: name, category: category}
+	}
+	fieldTypeRegistry.Store(types)
+	fieldTypeMutex.Unlock()
+}
+
+// registeredFieldTypes returns the current registrations, the map must not be modified.
+func registeredFieldTypes() map[uint8]fieldTypeInfo {
+	types, _ := fieldTypeRegistry.Load().(map[uint8]fieldTypeInfo)
+	return types
+}
+
+func registeredFieldType(code uint8) (fieldTypeInfo, bool) {
+	t, ok := registeredFieldTypes()[code]
+	return t, ok
+}
+
+// registeredCategory returns the category of a code registered with a category other than its
+// builtin one, the builtin handling of the code does not apply to it.
+func registeredCategory(code uint8) (TypeCategory, bool) {
+	t, ok := registeredFieldType(code)
+	if !ok || t.category == categoryFor(code) {
+		return CategoryUnknown, false
+	}
+	return t.category, true
+}
+
+// builtin field types handling the Go types of registered categories
+var categoryFieldTypes = map[TypeCategory]uint8{
+	CategoryInteger:  fieldTypeLongLong,
+	CategoryFloat:    fieldTypeDouble,
+	CategoryDecimal:  fieldTypeNewDecimal,
+	CategoryText:     fieldTypeVarString,
+	CategoryBlob:     fieldTypeLongBLOB,
+	CategoryTemporal: fieldTypeDateTime,
+	CategoryJSON:     fieldTypeJSON,
+	CategoryBit:      fieldTypeBit,
+	CategoryVector:   fieldTypeVector,
+}
+
+// asBuiltin returns the field with the builtin type code of its registered category,
+// ok is false if the category has none. Other fields are returned unchanged.
+func (f mysqlField) asBuiltin() (mysqlField, bool) {
+	category, ok := registeredCategory(f.fieldType)
+	if !ok {
+		return f, true
+	}
+	f.fieldType, ok = categoryFieldTypes[category]
+	if category == CategoryBlob {
+		f.charSet = binaryCharSet
+	}
+	return f, ok
+}
+
+// declare a field of a registered category by name, its parameters are unknown
+func (f mysqlField) registeredDeclaration(category TypeCategory, opts declarationOptions) string {
+	decl := f.MysqlType()
+	if opts.typeOnly {
+		return decl
+	}
+	switch category {
+	case CategoryInteger, CategoryFloat, CategoryDecimal:
+		if f.IsUnsigned() {
+			decl += " UNSIGNED"
+		}
+		if f.IsZerofill() && !opts.modern {
+			decl += " ZEROFILL"
+		}
+	case CategoryText, CategoryEnum, CategorySet:
+		if opts.charset {
+			decl += f.charsetClause()
+		}
+	}
+	if f.IsNotNull() {
+		decl += " NOT NULL"
+	}
+	return decl
+}
+
+// TypeNameOverride returns the type name reported for the column instead of name,
+// the builtin or registered name of its type.
+// info.Column() provides the inspection methods, but the override must not call
+// MysqlType or the declaration methods.
+type TypeNameOverride func(info ColumnInfo, name string) string
+
+// map[uint8]TypeNameOverride of the overrides set by SetTypeNameOverride,
+// replaced by a copy under fieldTypeMutex on changes and read without locking
+var typeNameOverrides atomic.Value
+
+// SetTypeNameOverride customizes the type names of a MySQL field type code,
+// e.g. to emit MariaDB or application specific names like BOOLEAN for TINYINT(1).
+//
+// The override changes the results of MysqlType and the declaration methods like MysqlDeclaration,
+// the package still uses the builtin names internally. A nil override removes it.
+func SetTypeNameOverride(code byte, override TypeNameOverride) {
+	fieldTypeMutex.Lock()
+	overrides := copyTypeNameOverrides(currentTypeNameOverrides())
+	if override == nil {
+		delete(overrides, code)
+	} else {
+		overrides[code] = override
+	}
+	typeNameOverrides.Store(overrides)
+	fieldTypeMutex.Unlock()
+}
+
+// currentTypeNameOverrides returns the current overrides, the map must not be modified.
+func currentTypeNameOverrides() map[uint8]TypeNameOverride {
+	overrides, _ := typeNameOverrides.Load().(map[uint8]TypeNameOverride)
+	return overrides
+}
+
+func typeNameOverride(code uint8) TypeNameOverride {
+	return currentTypeNameOverrides()[code]
+}
+
 type parameterType uint
 
 const (
@@ -429,7 +1121,9 @@ func (f mysqlField) MysqlParameters() parameterType {
 		// DECIMAL and NUMERIC declarations have one optional parameter (length) and may use decimals
 		fieldTypeDecimal, fieldTypeNewDecimal,
 		// REAL, FLOAT and DOUBLE declarations have one optional parameter (length, will also use decimals when length is given)
-		fieldTypeFloat, fieldTypeDouble:
+		fieldTypeFloat, fieldTypeDouble,
+		// VECTOR declarations have one optional parameter (dimensions)
+		fieldTypeVector:
 		return ParamMayLength
 	case // VARCHAR and VARBINARY declarations have one mandatory parameter (length)
 		fieldTypeVarChar, fieldTypeVarString:
@@ -441,6 +1135,67 @@ func (f mysqlField) MysqlParameters() parameterType {
 	return ParamUnknown
 }
 
+// collect strings and the content of string slices, fails for other types or no values
+func stringValues(args []interface{}) ([]string, bool) {
+	var values []string
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			values = append(values, v)
+		case []string:
+			values = append(values, v...)
+		default:
+			return nil, false
+		}
+	}
+	return values, len(values) > 0
+}
+
+// RawValues is a preformatted list of quoted and escaped ENUM or SET values, e.g. "'a','b'".
+// MysqlDeclaration uses it as is.
+type RawValues string
+
+// isQuotedList reports whether s is a comma separated list of single quoted MySQL string literals.
+func isQuotedList(s string) bool {
+	for {
+		s = strings.TrimLeft(s, " ")
+		if !strings.HasPrefix(s, "'") {
+			return false
+		}
+		// find the closing quote, skipping doubled quotes and backslash escapes
+		i := 1
+		for ; i < len(s); i++ {
+			if s[i] == '\\' {
+				i++
+			} else if s[i] == '\'' {
+				if i+1 < len(s) && s[i+1] == '\'' {
+					i++
+				} else {
+					break
+				}
+			}
+		}
+		if i >= len(s) {
+			return false
+		}
+		s = strings.TrimLeft(s[i+1:], " ")
+		if s == "" {
+			return true
+		}
+		if s[0] != ',' {
+			return false
+		}
+		s = s[1:]
+	}
+}
+
+var valueEscaper = strings.NewReplacer(`'`, `''`, `\`, `\\`)
+
+// quote a value as a MySQL string literal
+func quoteValue(value string) string {
+	return "'" + valueEscaper.Replace(value) + "'"
+}
+
 type paramErr string
 
 func (p paramErr) Error() string {
@@ -454,10 +1209,59 @@ func (p paramErr) Error() string {
 // For VARCHAR and VARBINARY types, args must be one int: length.
 // For DECIMAL and NUMERIC types, it may be none or one int: length.
 // For DATETIME, TIME, TIMESTAMP, decimals is used for microseconds.
-// For FLOAT, DOUBLE and REAL floating point types, it is optional and, when given, must be two ints: length and decimals.
-// For SETs and ENUMs, it specifies the possible values.
+// For VECTOR, args is optional and may be one int: dimensions. It is derived from the length if omitted.
+// For FLOAT, DOUBLE and REAL floating point types, it is optional and may be one int: length.
+// Their scale is taken from decimals; when the server reports it as unspecified (31),
+// the type is declared without parameters or, for FLOAT with a length, as FLOAT(p) with
+// the length as precision in bits. DOUBLE has no precision without a scale, a length is
+// an error then. Without args, the reported length is used.
+// For SETs and ENUMs, it specifies the possible values as strings or []string, they are quoted and escaped.
+// A single RawValues or a single string that is already a list of quoted values, e.g. "'a','b'",
+// is used as is; values passed as []string are always quoted.
 // For all other types, args must be empty.
 func (f mysqlField) MysqlDeclaration(args ...interface{}) (string, error) {
+	return f.declaration(args, declarationOptions{})
+}
+
+// get a type declaration including the character set and collation
+func (f mysqlField) MysqlDeclarationWithCharset(args ...interface{}) (string, error) {
+	return f.declaration(args, declarationOptions{charset: true})
+}
+
+// DeclarationMode selects the MySQL dialect of MysqlDeclarationFor
+type DeclarationMode int
+
+const (
+	// DeclarationLegacy creates declarations like MysqlDeclaration
+	DeclarationLegacy DeclarationMode = iota
+	// DeclarationModern omits ZEROFILL, deprecated in MySQL 8
+	DeclarationModern
+)
+
+// get a type declaration in the given mode
+func (f mysqlField) MysqlDeclarationFor(mode DeclarationMode, args ...interface{}) (string, error) {
+	return f.declaration(args, declarationOptions{modern: mode == DeclarationModern})
+}
+
+// get the type with its parameters, but without attributes
+func (f mysqlField) MysqlTypeOnly(args ...interface{}) (string, error) {
+	return f.declaration(args, declarationOptions{typeOnly: true})
+}
+
+// decimalsNotFixed is reported as decimals of FLOAT and DOUBLE columns without a fixed scale
+const decimalsNotFixed = 31
+
+// declarationOptions modify the output of declaration
+type declarationOptions struct {
+	// add CHARACTER SET and COLLATE
+	charset bool
+	// omit all attributes, e.g. UNSIGNED and NOT NULL
+	typeOnly bool
+	// omit ZEROFILL, deprecated since MySQL 8.0.17
+	modern bool
+}
+
+func (f mysqlField) declaration(args []interface{}, opts declarationOptions) (string, error) {
 	const (
 		unsigned = " UNSIGNED"
 		notNull  = " NOT NULL"
@@ -469,13 +1273,29 @@ func (f mysqlField) MysqlDeclaration(args ...interface{}) (string, error) {
 		errNone       = paramErr("parameter error, must be none")
 		errMayLength  = paramErr("parameter error, must be none or one int (length)")
 		errMustLength = paramErr("parameter error, must be one int (length)")
-		errEnumOrSet  = paramErr("parameter error, must be at least one entry")
+		errEnumOrSet  = paramErr("parameter error, must be at least one string")
 	)
 	// fail fast if we can't provide a declaration
 	if f.fieldType == fieldTypeNULL {
 		return "", errNil
 	}
-	var param, us, nn, zf, bin string
+	if category, ok := registeredCategory(f.fieldType); ok {
+		if len(args) != 0 {
+			return "", errNone
+		}
+		return f.registeredDeclaration(category, opts), nil
+	}
+	var param, us, nn, zf, bin, cs string
+	if opts.charset {
+		switch f.fieldType {
+		case fieldTypeVarChar, fieldTypeVarString, fieldTypeString, fieldTypeEnum, fieldTypeSet:
+			cs = f.charsetClause()
+		default:
+			if f.isTextBlob() {
+				cs = f.charsetClause()
+			}
+		}
+	}
 	if f.IsNotNull() {
 		// any type may be "NOT NULL"
 		nn = notNull
@@ -483,8 +1303,20 @@ func (f mysqlField) MysqlDeclaration(args ...interface{}) (string, error) {
 	switch f.fieldType {
 	case fieldTypeFloat, fieldTypeDouble,
 		fieldTypeDecimal, fieldTypeNewDecimal:
-		if len(args) == 1 {
+		switch {
+		case f.IsFloatingPoint() && f.decimals == decimalsNotFixed:
+			// the server did not report a scale, FLOAT(m,31) is invalid
+			if len(args) == 1 {
+				if f.fieldType != fieldTypeFloat {
+					// only FLOAT has a precision without a scale
+					return "", errNone
+				}
+				param = fmt.Sprintf("(%d)", args[0])
+			}
+		case len(args) == 1:
 			param = fmt.Sprintf("(%d,%d)", args[0], f.decimals)
+		case f.IsFloatingPoint() && f.length > 0:
+			param = fmt.Sprintf("(%d,%d)", f.length, f.decimals)
 		}
 		fallthrough
 	case // numeric types may be unsigned or zerofill
@@ -492,7 +1324,7 @@ func (f mysqlField) MysqlDeclaration(args ...interface{}) (string, error) {
 		if f.IsUnsigned() {
 			us = unsigned
 		}
-		if f.IsZerofill() {
+		if f.IsZerofill() && !opts.modern {
 			zf = zerofill
 		}
 	case fieldTypeBit:
@@ -506,7 +1338,7 @@ func (f mysqlField) MysqlDeclaration(args ...interface{}) (string, error) {
 		// nothing to be done for these types
 	case // only string types may be binary
 		fieldTypeVarChar, fieldTypeVarString:
-		if f.IsBinary() {
+		if f.IsBinary() && !strings.Contains(cs, " COLLATE ") {
 			bin = binary
 		}
 		if len(args) != 1 {
@@ -514,7 +1346,7 @@ func (f mysqlField) MysqlDeclaration(args ...interface{}) (string, error) {
 		}
 		param = fmt.Sprintf("(%d)", args[0])
 	case fieldTypeString:
-		if f.IsBinary() {
+		if f.IsBinary() && !strings.Contains(cs, " COLLATE ") {
 			bin = binary
 		}
 		if len(args) == 1 {
@@ -524,14 +1356,62 @@ func (f mysqlField) MysqlDeclaration(args ...interface{}) (string, error) {
 		if f.decimals > 0 {
 			param = fmt.Sprintf("(%d)", f.decimals)
 		}
+	case fieldTypeVector:
+		switch {
+		case len(args) == 1:
+			param = fmt.Sprintf("(%d)", args[0])
+		case f.length > 0:
+			// the length is in bytes, each dimension is a float32
+			param = fmt.Sprintf("(%d)", f.length/4)
+		}
 
 	case fieldTypeEnum, fieldTypeSet:
-		if len(args) == 0 {
+		if len(args) == 1 {
+			if raw, ok := args[0].(RawValues); ok && raw != "" {
+				param = "(" + string(raw) + ")"
+				break
+			}
+			if list, ok := args[0].(string); ok && isQuotedList(list) {
+				param = "(" + list + ")"
+				break
+			}
+		}
+		values, ok := stringValues(args)
+		if !ok {
 			return "", errEnumOrSet
 		}
-		param = fmt.Sprintf("(%v)", args...)
+		for i, v := range values {
+			values[i] = quoteValue(v)
+		}
+		param = "(" + strings.Join(values, ",") + ")"
 	default:
 		return "", errUnknown
 	}
-	return mysqlNameFor(f.fieldType) + param + bin + us + zf + nn, nil
+	if opts.typeOnly {
+		return f.MysqlType() + param, nil
+	}
+	return f.MysqlType() + param + bin + cs + us + zf + nn, nil
+}
+
+// convert raw SET values to []string
+func (f mysqlField) ConvertValue(src interface{}) (interface{}, error) {
+	if f.fieldType != fieldTypeSet {
+		return src, nil
+	}
+	var value string
+	switch v := src.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		value = string(v)
+	case string:
+		value = v
+	default:
+		return nil, fmt.Errorf("can't convert %T to the members of a SET", src)
+	}
+	if value == "" {
+		return []string{}, nil
+	}
+	// members can't contain commas
+	return strings.Split(value, ","), nil
 }
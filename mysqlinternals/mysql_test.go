@@ -9,11 +9,25 @@
 package mysqlinternals
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
-	_ "github.com/go-sql-driver/mysql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/arnehormann/sqlinternals"
+	"github.com/go-sql-driver/mysql"
+	"math"
+	"math/big"
 	"os"
 	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+	"unsafe"
 )
 
 var dsn string
@@ -147,7 +161,7 @@ func TestRows(t *testing.T) {
 			hasValue:      true,
 			expectedValue: []byte("Hi"),
 		},
-		// TODO: add more tests (many columns, NULL column, different types...)
+		// more types are covered without a database in TestColumnsOffline
 	}
 	for _, setup := range testSetups {
 		testRow(t, setup, false)
@@ -179,3 +193,3860 @@ func TestIsBinary(t *testing.T) {
 		}
 	}
 }
+
+func TestSmallestGoIntType(t *testing.T) {
+	tests := []struct {
+		field  mysqlField
+		goType reflect.Type
+		fails  bool
+	}{
+		{field: mysqlField{fieldType: fieldTypeTiny, flags: flagUnsigned}, goType: reflect.TypeOf(uint8(0))},
+		{field: mysqlField{fieldType: fieldTypeTiny}, goType: reflect.TypeOf(int8(0))},
+		{field: mysqlField{fieldType: fieldTypeInt24, flags: flagUnsigned}, goType: reflect.TypeOf(uint32(0))},
+		{field: mysqlField{fieldType: fieldTypeLongLong}, goType: reflect.TypeOf(int64(0))},
+		{field: mysqlField{fieldType: fieldTypeDouble, flags: flagUnsigned}, fails: true},
+		{field: mysqlField{fieldType: fieldTypeVarChar}, fails: true},
+	}
+	for _, setup := range tests {
+		refl, err := setup.field.SmallestGoIntType()
+		if setup.fails && err == nil {
+			t.Errorf("expected an error for %s\n", setup.field.MysqlType())
+		}
+		if !setup.fails && err != nil {
+			t.Errorf("did not expect an error for %s, got '%v'\n", setup.field.MysqlType(), err)
+		}
+		if refl != setup.goType {
+			t.Errorf("type '%v' did not match expected '%v'\n", refl, setup.goType)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		field    mysqlField
+		problems int
+	}{
+		{field: mysqlField{fieldType: fieldTypeLong, flags: flagNotNULL | flagPriKey | flagAutoIncrement}},
+		{field: mysqlField{fieldType: fieldTypeLong, flags: flagUnsigned | flagZeroFill}},
+		{field: mysqlField{fieldType: fieldTypeVarChar, flags: flagBinary}},
+		{field: mysqlField{fieldType: fieldTypeVarChar, flags: flagUnsigned}, problems: 1},
+		{field: mysqlField{fieldType: fieldTypeDate, flags: flagUnsigned | flagZeroFill}, problems: 2},
+		{field: mysqlField{fieldType: fieldTypeBLOB, flags: flagAutoIncrement}, problems: 1},
+		{field: mysqlField{fieldType: fieldTypeTiny, flags: flagZeroFill}, problems: 1},
+		{field: mysqlField{fieldType: fieldTypeShort, decimals: 2}, problems: 1},
+		{field: mysqlField{fieldType: fieldTypeLongLong, flags: flagPriKey}, problems: 1},
+		{field: mysqlField{fieldType: 0x42}, problems: 1},
+	}
+	for _, setup := range tests {
+		if problems := setup.field.Validate(); len(problems) != setup.problems {
+			t.Errorf("expected %d problems for %#v, got %q\n", setup.problems, setup.field, problems)
+		}
+	}
+}
+
+func TestDriverRowsFastPath(t *testing.T) {
+	rows := fakeQuery(t, newFakeRows(false, []mysqlField{{name: "a", fieldType: fieldTypeLong}}))
+	defer rows.Close()
+	inspected, err := sqlinternals.Inspect(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dRows, _, stage := driverRows(rows); stage != StageNone || dRows != inspected {
+		t.Errorf("expected the driver.Rows from Inspect, got %#v (%v)\n", dRows, stage)
+	}
+	db := sql.OpenDB(&fakeDB{rows: newFakeRows(false, []mysqlField{{name: "a", fieldType: fieldTypeLong}})})
+	defer db.Close()
+	row := db.QueryRow("fake")
+	if _, _, stage := driverRows(row); stage != StageNone {
+		t.Errorf("expected sql.Row to work, got %v\n", stage)
+	}
+}
+
+func benchmarkDriverRows(b *testing.B, useQueryRow bool, find func(interface{}) (driver.Rows, Layout, InspectStage)) {
+	db := sql.OpenDB(&fakeDB{rows: newFakeRows(false, []mysqlField{{name: "a", fieldType: fieldTypeLong}})})
+	defer db.Close()
+	var rowOrRows interface{}
+	if useQueryRow {
+		rowOrRows = db.QueryRow("fake")
+	} else {
+		rows, err := db.Query("fake")
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer rows.Close()
+		rowOrRows = rows
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, stage := find(rowOrRows); stage != StageNone {
+			b.Fatal("could not retrieve driver.Rows")
+		}
+	}
+}
+
+// inspectedRows is driverRows without the fast path for sql.Rows
+func inspectedRows(rowOrRows interface{}) (driver.Rows, Layout, InspectStage) {
+	rows, err := sqlinternals.Inspect(rowOrRows)
+	if err != nil {
+		return nil, Layout{}, StageNotRows
+	}
+	dRows, _ := rows.(driver.Rows)
+	layout, stage := checkedRows(dRows)
+	return dRows, layout, stage
+}
+
+func BenchmarkDriverRowsFromRows(b *testing.B) {
+	benchmarkDriverRows(b, false, driverRows)
+}
+
+func BenchmarkDriverRowsFromRowsInspected(b *testing.B) {
+	benchmarkDriverRows(b, false, inspectedRows)
+}
+
+func BenchmarkDriverRowsFromRow(b *testing.B) {
+	benchmarkDriverRows(b, true, driverRows)
+}
+
+func TestRegisterFieldType(t *testing.T) {
+	const code = 0xe0
+	defer RegisterFieldType(code, "", CategoryUnknown)
+	field := mysqlField{fieldType: code}
+	if name := field.MysqlType(); name != "" {
+		t.Fatalf("type code %#x must be unknown, got '%s'\n", code, name)
+	}
+	RegisterFieldType(code, "MEDIUMINT", CategoryInteger)
+	if name := field.MysqlType(); name != "MEDIUMINT" {
+		t.Errorf("expected registered name 'MEDIUMINT', got '%s'\n", name)
+	}
+	if !field.IsInteger() || !field.IsNumber() || field.IsText() {
+		t.Errorf("registered category was not used by the predicates\n")
+	}
+	if refl, err := field.ReflectGoType(); err != nil || refl != typeInt64 {
+		t.Errorf("expected int64 for the registered integer type, got %v (%v)\n", refl, err)
+	}
+	unsigned := mysqlField{fieldType: code, flags: flagUnsigned | flagNotNULL}
+	if refl, err := unsigned.SmallestGoIntType(); err != nil || refl != typeUint64 {
+		t.Errorf("expected uint64 for the registered unsigned integer type, got %v (%v)\n", refl, err)
+	}
+	if decl, err := unsigned.MysqlDeclaration(); err != nil || decl != "MEDIUMINT UNSIGNED NOT NULL" {
+		t.Errorf("expected 'MEDIUMINT UNSIGNED NOT NULL', got '%s' (%v)\n", decl, err)
+	}
+	if _, err := field.MysqlDeclaration(8); err == nil {
+		t.Errorf("expected an error for parameters of a registered type\n")
+	}
+	// a builtin code registered with another category
+	RegisterFieldType(fieldTypeGeometry, "UUID", CategoryText)
+	defer RegisterFieldType(fieldTypeGeometry, "", CategoryUnknown)
+	uuid := mysqlField{fieldType: fieldTypeGeometry}
+	if refl, err := uuid.ReflectGoType(); err != nil || refl != typeString {
+		t.Errorf("expected string for the registered text type, got %v (%v)\n", refl, err)
+	}
+	if _, err := uuid.SmallestGoIntType(); err == nil {
+		t.Errorf("expected an error for the integer type of a registered text type\n")
+	}
+	RegisterFieldType(code, "", CategoryUnknown)
+	if name := field.MysqlType(); name != "" {
+		t.Errorf("expected the registration to be removed, got '%s'\n", name)
+	}
+}
+
+func TestRowCount(t *testing.T) {
+	if n, ok := rowCount(&countedRows{numRows: 42}); !ok || n != 42 {
+		t.Errorf("expected (42, true), got (%d, %t)\n", n, ok)
+	}
+	if n, ok := rowCount(&uncountedRows{}); ok || n != 0 {
+		t.Errorf("expected (0, false), got (%d, %t)\n", n, ok)
+	}
+	if n, ok := EstimatedRowCount(nil); ok || n != 0 {
+		t.Errorf("expected (0, false) for nil, got (%d, %t)\n", n, ok)
+	}
+}
+
+func TestEstimatedRowCount(t *testing.T) {
+	// embedded for the methods, the name is unique to this test
+	type estimatedRows struct {
+		countedRows
+	}
+	rows := fakeQuery(t, &estimatedRows{countedRows{numRows: 42}})
+	defer rows.Close()
+	if n, ok := EstimatedRowCount(rows); ok || n != 0 {
+		t.Errorf("expected (0, false) for rows without a registered layout, got (%d, %t)\n", n, ok)
+	}
+	RegisterLayout("estimatedRows", func(reflect.Type) (Layout, error) {
+		return Layout{Columns: func(driver.Rows) []Column { return nil }}, nil
+	})
+	defer RegisterLayout("estimatedRows", nil)
+	if n, ok := EstimatedRowCount(rows); !ok || n != 42 {
+		t.Errorf("expected (42, true), got (%d, %t)\n", n, ok)
+	}
+	mysqlRows := fakeQuery(t, newFakeRows(false, []mysqlField{{name: "a", fieldType: fieldTypeLong}}))
+	defer mysqlRows.Close()
+	if n, ok := EstimatedRowCount(mysqlRows); ok || n != 0 {
+		t.Errorf("expected (0, false) for the rows of go-sql-driver, got (%d, %t)\n", n, ok)
+	}
+}
+
+func TestScanType(t *testing.T) {
+	tests := []struct {
+		field    mysqlField
+		scanType reflect.Type
+	}{
+		{field: mysqlField{fieldType: fieldTypeTiny, flags: flagNotNULL | flagUnsigned}, scanType: reflect.TypeOf(uint8(0))},
+		{field: mysqlField{fieldType: fieldTypeLong, flags: flagNotNULL}, scanType: reflect.TypeOf(int32(0))},
+		{field: mysqlField{fieldType: fieldTypeYear, flags: flagNotNULL | flagUnsigned}, scanType: reflect.TypeOf(uint16(0))},
+		{field: mysqlField{fieldType: fieldTypeLongLong}, scanType: reflect.TypeOf(sql.NullInt64{})},
+		{field: mysqlField{fieldType: fieldTypeDouble}, scanType: reflect.TypeOf(sql.NullFloat64{})},
+		{field: mysqlField{fieldType: fieldTypeVarString, flags: flagNotNULL}, scanType: reflect.TypeOf(sql.RawBytes{})},
+		{field: mysqlField{fieldType: fieldTypeNewDecimal}, scanType: reflect.TypeOf(sql.RawBytes{})},
+		{field: mysqlField{fieldType: fieldTypeDateTime, flags: flagNotNULL}, scanType: reflect.TypeOf(mysql.NullTime{})},
+	}
+	for _, setup := range tests {
+		if scanType := setup.field.ScanType(); scanType != setup.scanType {
+			t.Errorf("scan type '%v' for %s did not match expected '%v'\n",
+				scanType, setup.field.MysqlType(), setup.scanType)
+		}
+	}
+}
+
+func TestScanTypeMatchesColumnTypes(t *testing.T) {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, query := range []string{"SELECT 1, 'a', 1.5, NOW(), NULL", "SELECT ?, ?"} {
+		var queryArgs []interface{}
+		if query == "SELECT ?, ?" {
+			queryArgs = args(1, "a")
+		}
+		rows, err := db.Query(query, queryArgs...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		cols, err := Columns(rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		types, err := rows.ColumnTypes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, col := range cols {
+			if expected := types[i].ScanType(); col.ScanType() != expected {
+				t.Errorf("%s: scan type '%v' of column %d did not match expected '%v'\n",
+					query, col.ScanType(), i, expected)
+			}
+		}
+	}
+}
+
+func TestColumnsWithoutResult(t *testing.T) {
+	rows := fakeQuery(t, emptyRows{})
+	defer rows.Close()
+	cols, err := Columns(rows)
+	if err != ErrNoResultSet || cols != nil {
+		t.Errorf("expected no columns and ErrNoResultSet for a statement without result, got %v, '%v'\n", cols, err)
+	}
+	// the driver returns textRows without columns for statements like INSERT
+	rows = fakeQuery(t, newFakeRows(false, nil))
+	defer rows.Close()
+	cols, err = Columns(rows)
+	if err != ErrNoResultSet || cols != nil {
+		t.Errorf("expected no columns and ErrNoResultSet for a result without columns, got %v, '%v'\n", cols, err)
+	}
+	if _, err = ColumnsUnchecked(rows); err != ErrNoResultSet {
+		t.Errorf("expected ErrNoResultSet from ColumnsUnchecked, got '%v'\n", err)
+	}
+	if _, err = ReadColumnInfo(make([]ColumnInfo, 1), rows); err != ErrNoResultSet {
+		t.Errorf("expected ErrNoResultSet from ReadColumnInfo, got '%v'\n", err)
+	}
+	rows = fakeQuery(t, &uncountedRows{})
+	defer rows.Close()
+	if _, err = Columns(rows); err == nil {
+		t.Errorf("expected an error for rows of another driver\n")
+	}
+}
+
+func TestColumnsOfDML(t *testing.T) {
+	db := openTestSchema(t)
+	defer db.Close()
+	for _, stmt := range []string{
+		"DROP TABLE IF EXISTS dml",
+		"CREATE TABLE dml (id INT)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer db.Exec("DROP TABLE dml")
+	rows, err := db.Query("INSERT INTO dml VALUES (1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	if cols, err := Columns(rows); err != ErrNoResultSet {
+		t.Errorf("expected ErrNoResultSet for INSERT, got %#v, '%v'\n", cols, err)
+	}
+}
+
+func TestColumnsOfProcedure(t *testing.T) {
+	db := openTestSchema(t)
+	defer db.Close()
+	for _, stmt := range []string{
+		"DROP PROCEDURE IF EXISTS proc",
+		"CREATE PROCEDURE proc() BEGIN SELECT 1 AS a, 'x' AS b; SELECT NOW() AS c; END",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer db.Exec("DROP PROCEDURE proc")
+	rows, err := db.Query("CALL proc()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for i, expected := range [][]TypeCategory{
+		{CategoryInteger, CategoryText},
+		{CategoryTemporal},
+	} {
+		if i > 0 && !rows.NextResultSet() {
+			t.Fatalf("missing result set %d: %v\n", i, rows.Err())
+		}
+		cols, err := ColumnsForCurrentResultSet(rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cols) != len(expected) {
+			t.Fatalf("result set %d: expected %d columns, got %#v\n", i, len(expected), cols)
+		}
+		for j, category := range expected {
+			if cols[j].Category() != category {
+				t.Errorf("result set %d, column %d: expected category %d, got %d\n", i, j, category, cols[j].Category())
+			}
+		}
+	}
+	if rows.NextResultSet() {
+		t.Errorf("expected no further result set with columns\n")
+	}
+}
+
+func TestGeometryType(t *testing.T) {
+	if name, ok := (mysqlField{fieldType: fieldTypeGeometry}).GeometryType(); ok || name != "GEOMETRY" {
+		t.Errorf("expected (\"GEOMETRY\", false), got (%q, %t)\n", name, ok)
+	}
+	if name, ok := (mysqlField{fieldType: fieldTypeLong}).GeometryType(); ok || name != "" {
+		t.Errorf("expected no geometry type for INT, got (%q, %t)\n", name, ok)
+	}
+}
+
+func TestGeometryTypeSpatialColumns(t *testing.T) {
+	db := openTestSchema(t)
+	defer db.Close()
+	for _, stmt := range []string{
+		"DROP TABLE IF EXISTS spatial",
+		"CREATE TABLE spatial (p POINT, poly POLYGON)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer db.Exec("DROP TABLE spatial")
+	rows, err := db.Query("SELECT p, poly FROM spatial")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	cols, err := Columns(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, col := range cols {
+		// the subtype is not sent, POINT and POLYGON are both GEOMETRY
+		if name, ok := col.GeometryType(); ok || name != "GEOMETRY" {
+			t.Errorf("column %s: expected (\"GEOMETRY\", false), got (%q, %t)\n", col.Name(), name, ok)
+		}
+	}
+}
+
+func TestDecimalSize(t *testing.T) {
+	tests := []struct {
+		field     mysqlField
+		precision int64
+		scale     int64
+		ok        bool
+	}{
+		// DECIMAL(10,2)
+		{field: mysqlField{fieldType: fieldTypeNewDecimal, length: 12, decimals: 2}, precision: 10, scale: 2, ok: true},
+		// DECIMAL(10,2) UNSIGNED
+		{field: mysqlField{fieldType: fieldTypeNewDecimal, length: 12, decimals: 2, flags: flagUnsigned}, precision: 10, scale: 2, ok: true},
+		// DECIMAL(5)
+		{field: mysqlField{fieldType: fieldTypeNewDecimal, length: 6}, precision: 5, ok: true},
+		// INT
+		{field: mysqlField{fieldType: fieldTypeLong, length: 11}},
+	}
+	for _, setup := range tests {
+		precision, scale, ok := setup.field.DecimalSize()
+		if precision != setup.precision || scale != setup.scale || ok != setup.ok {
+			t.Errorf("expected (%d, %d, %t) for %#v, got (%d, %d, %t)\n",
+				setup.precision, setup.scale, setup.ok, setup.field, precision, scale, ok)
+		}
+	}
+}
+
+func TestColumnsInTransaction(t *testing.T) {
+	const query = "SELECT 1, 'a', NULL"
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	rows, err := db.Query(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	expected, err := Columns(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	txRows, err := tx.Query(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer txRows.Close()
+	cols, err := Columns(txRows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cols, expected) {
+		t.Errorf("columns in transaction %#v did not match %#v\n", cols, expected)
+	}
+}
+
+func TestColumnsOnConn(t *testing.T) {
+	const query = "SELECT 1, 'a', NULL"
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	rows, err := db.Query(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	expected, err := Columns(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	connRows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer connRows.Close()
+	cols, err := Columns(connRows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cols, expected) {
+		t.Errorf("columns on *sql.Conn %#v did not match %#v\n", cols, expected)
+	}
+	cols, err = Columns(conn.QueryRowContext(ctx, query))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cols, expected) {
+		t.Errorf("columns of row on *sql.Conn %#v did not match %#v\n", cols, expected)
+	}
+}
+
+func TestColumnsOnConnOffline(t *testing.T) {
+	fields := []mysqlField{{name: "a", fieldType: fieldTypeLongLong}, {name: "b", fieldType: fieldTypeVarString}}
+	db := sql.OpenDB(&fakeDB{rows: newFakeRows(true, fields)})
+	defer db.Close()
+	rows, err := db.Query("fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := Columns(rows)
+	rows.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	connRows, err := conn.QueryContext(ctx, "fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer connRows.Close()
+	cols, isBinary, err := ColumnsAndProtocol(connRows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isBinary || !reflect.DeepEqual(cols, expected) {
+		t.Errorf("columns on *sql.Conn %#v did not match %#v\n", cols, expected)
+	}
+}
+
+func TestEnumDeclaration(t *testing.T) {
+	tests := []struct {
+		field mysqlField
+		args  []interface{}
+		decl  string
+		fails bool
+	}{
+		{field: mysqlField{fieldType: fieldTypeEnum}, args: args("a", "b"), decl: "ENUM('a','b')"},
+		{field: mysqlField{fieldType: fieldTypeSet}, args: args([]string{"a", "b"}), decl: "SET('a','b')"},
+		{field: mysqlField{fieldType: fieldTypeEnum, flags: flagNotNULL}, args: args("it's", "a,b", `c\`), decl: `ENUM('it''s','a,b','c\\') NOT NULL`},
+		{field: mysqlField{fieldType: fieldTypeEnum}, args: args(RawValues("'a','b'")), decl: "ENUM('a','b')"},
+		// a single preformatted string is used as is, like before values were quoted
+		{field: mysqlField{fieldType: fieldTypeEnum}, args: args("'a','b'"), decl: "ENUM('a','b')"},
+		{field: mysqlField{fieldType: fieldTypeSet}, args: args(`'it''s', 'c\\'`), decl: `SET('it''s', 'c\\')`},
+		// other strings are quoted
+		{field: mysqlField{fieldType: fieldTypeEnum}, args: args("'a"), decl: "ENUM('''a')"},
+		{field: mysqlField{fieldType: fieldTypeEnum}, args: args("'a' b"), decl: "ENUM('''a'' b')"},
+		{field: mysqlField{fieldType: fieldTypeEnum}, args: args("'a'", "'b'"), decl: "ENUM('''a''','''b''')"},
+		{field: mysqlField{fieldType: fieldTypeEnum}, args: args([]string{"'a'"}), decl: "ENUM('''a''')"},
+		{field: mysqlField{fieldType: fieldTypeEnum}, args: args(RawValues("")), fails: true},
+		{field: mysqlField{fieldType: fieldTypeSet}, args: args(RawValues("'a'"), "b"), fails: true},
+		{field: mysqlField{fieldType: fieldTypeEnum}, fails: true},
+		{field: mysqlField{fieldType: fieldTypeSet}, args: args(1, 2), fails: true},
+	}
+	for _, setup := range tests {
+		decl, err := setup.field.MysqlDeclaration(setup.args...)
+		if setup.fails != (err != nil) {
+			t.Errorf("unexpected error state for %v: '%v'\n", setup.args, err)
+		}
+		if decl != setup.decl {
+			t.Errorf("declaration '%s' did not match expected '%s'\n", decl, setup.decl)
+		}
+	}
+}
+
+func TestColumnsFromDriverRows(t *testing.T) {
+	fields := []mysqlField{
+		{name: "id", fieldType: fieldTypeLong, flags: flagNotNULL | flagPriKey},
+		{name: "name", fieldType: fieldTypeVarString},
+	}
+	cols, err := ColumnsFromDriverRows(newFakeRows(true, fields))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cols) != len(fields) {
+		t.Fatalf("expected %d columns, got %d\n", len(fields), len(cols))
+	}
+	for i, col := range cols {
+		if col != fields[i] {
+			t.Errorf("column %d: %#v did not match %#v\n", i, col, fields[i])
+		}
+	}
+	if _, err = ColumnsFromDriverRows((*textRows)(nil)); err == nil {
+		t.Errorf("expected an error for nil rows\n")
+	}
+	if _, err = ColumnsFromDriverRows(&uncountedRows{}); err == nil {
+		t.Errorf("expected an error for rows of another driver\n")
+	}
+}
+
+func TestCanConvertAnonymous(t *testing.T) {
+	type named struct {
+		a   int
+		b   string
+		sub struct {
+			c bool
+		}
+	}
+	anonymous := reflect.TypeOf(struct {
+		a   int
+		b   string
+		sub struct {
+			c bool
+		}
+	}{})
+	mismatch := reflect.TypeOf(struct {
+		a   int
+		b   string
+		sub struct {
+			d bool
+		}
+	}{})
+	if !canConvert(reflect.TypeOf(named{}), anonymous) || !canConvert(anonymous, reflect.TypeOf(named{})) {
+		t.Errorf("named and structurally identical anonymous structs must be convertible\n")
+	}
+	if canConvert(reflect.TypeOf(named{}), mismatch) {
+		t.Errorf("fields of anonymous structs must be compared\n")
+	}
+	type renamed named
+	if canConvert(reflect.TypeOf(named{}), reflect.TypeOf(renamed{})) {
+		t.Errorf("differently named structs must not be convertible\n")
+	}
+}
+
+func TestUnsignedFloat(t *testing.T) {
+	field := mysqlField{fieldType: fieldTypeFloat, flags: flagUnsigned, decimals: 2}
+	if field.IsSigned() {
+		t.Errorf("FLOAT UNSIGNED must not be signed\n")
+	}
+	if !(mysqlField{fieldType: fieldTypeFloat}).IsSigned() {
+		t.Errorf("FLOAT must be signed\n")
+	}
+	if (mysqlField{fieldType: fieldTypeVarChar}).IsSigned() {
+		t.Errorf("VARCHAR must not be signed\n")
+	}
+	decl, err := field.MysqlDeclaration(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decl != "FLOAT(7,2) UNSIGNED" {
+		t.Errorf("SQL: type '%s' did not match expected 'FLOAT(7,2) UNSIGNED'\n", decl)
+	}
+	if refl, err := field.ReflectGoType(); err != nil || refl != reflect.TypeOf(float32(0)) {
+		t.Errorf("Go: type '%v' did not match expected 'float32', error '%v'\n", refl, err)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	// mimic a driver with a different mysqlField
+	type mysqlField struct {
+		tableName string
+		name      string
+		length    uint32
+		flags     fieldFlag
+		fieldType byte
+		decimals  byte
+		charSet   uint8
+		unknown   uint64
+	}
+	type resultSet struct {
+		columns     []mysqlField
+		columnNames []string
+		done        bool
+	}
+	type mysqlRows struct {
+		mc     *mysqlConn
+		rs     resultSet
+		finish func()
+	}
+	type textRows struct {
+		mysqlRows
+		driver.Rows
+	}
+	var messages []string
+	defer func(logger func(string)) {
+		Logger = logger
+	}(Logger)
+	Logger = func(message string) {
+		messages = append(messages, message)
+	}
+	if err := initOffsets(reflect.TypeOf(&textRows{})); err == nil {
+		t.Fatal("expected an error for a mismatched mysqlField")
+	}
+	if len(messages) != 1 || !strings.Contains(messages[0], "unknown") {
+		t.Errorf("expected a message with the driver's mysqlField, got %q\n", messages)
+	}
+}
+
+func TestCategory(t *testing.T) {
+	categories := map[byte]TypeCategory{
+		fieldTypeDecimal:    CategoryDecimal,
+		fieldTypeTiny:       CategoryInteger,
+		fieldTypeShort:      CategoryInteger,
+		fieldTypeLong:       CategoryInteger,
+		fieldTypeFloat:      CategoryFloat,
+		fieldTypeDouble:     CategoryFloat,
+		fieldTypeNULL:       CategoryNull,
+		fieldTypeTimestamp:  CategoryTemporal,
+		fieldTypeLongLong:   CategoryInteger,
+		fieldTypeInt24:      CategoryInteger,
+		fieldTypeDate:       CategoryTemporal,
+		fieldTypeTime:       CategoryTemporal,
+		fieldTypeDateTime:   CategoryTemporal,
+		fieldTypeYear:       CategoryTemporal,
+		fieldTypeNewDate:    CategoryTemporal,
+		fieldTypeVarChar:    CategoryText,
+		fieldTypeBit:        CategoryBit,
+		fieldTypeJSON:       CategoryJSON,
+		fieldTypeNewDecimal: CategoryDecimal,
+		fieldTypeEnum:       CategoryEnum,
+		fieldTypeSet:        CategorySet,
+		fieldTypeTinyBLOB:   CategoryBlob,
+		fieldTypeMediumBLOB: CategoryBlob,
+		fieldTypeLongBLOB:   CategoryBlob,
+		fieldTypeBLOB:       CategoryBlob,
+		fieldTypeVarString:  CategoryText,
+		fieldTypeString:     CategoryText,
+		fieldTypeGeometry:   CategoryGeometry,
+		fieldTypeVector:     CategoryVector,
+		0x42:                CategoryUnknown,
+	}
+	for fieldType, expected := range categories {
+		if category := (mysqlField{fieldType: fieldType}).Category(); category != expected {
+			t.Errorf("category %d of type code %#x did not match expected %d\n", category, fieldType, expected)
+		}
+	}
+}
+
+func TestVector(t *testing.T) {
+	field := mysqlField{fieldType: fieldTypeVector, length: 12, flags: flagNotNULL}
+	if !field.IsVector() || field.IsBlob() || field.MysqlType() != "VECTOR" {
+		t.Errorf("VECTOR was not detected\n")
+	}
+	if decl, err := field.MysqlDeclaration(); err != nil || decl != "VECTOR(3) NOT NULL" {
+		t.Errorf("SQL: type '%s' did not match expected 'VECTOR(3) NOT NULL', error '%v'\n", decl, err)
+	}
+	if refl, err := field.ReflectGoType(); err != nil || refl != reflect.TypeOf([]byte{}) {
+		t.Errorf("Go: type '%v' did not match expected '[]byte', error '%v'\n", refl, err)
+	}
+	// the driver has no scan type for VECTOR, it only reads it with the text protocol
+	if scanType := field.ScanType(); scanType != typeUnknown {
+		t.Errorf("expected the driver's unknown scan type, got %v\n", scanType)
+	}
+	if text, binary := field.DriverValueKind(false, false), field.DriverValueKind(true, false); text != reflect.Slice || binary != reflect.Invalid {
+		t.Errorf("expected the driver values %v and %v, got %v and %v\n", reflect.Slice, reflect.Invalid, text, binary)
+	}
+	if encoding := field.WireEncoding(true); encoding != WireLengthEncoded {
+		t.Errorf("expected a length-encoded VECTOR, got %v\n", encoding)
+	}
+}
+
+func TestVectorColumn(t *testing.T) {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var version string
+	if err = db.QueryRow("SELECT VERSION()").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if major, _ := strconv.Atoi(strings.SplitN(version, ".", 2)[0]); major < 9 || strings.Contains(version, "MariaDB") {
+		t.Skip("VECTOR requires MySQL 9, server is " + version)
+	}
+	rows, err := db.Query("SELECT STRING_TO_VECTOR('[1,2,3]')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	cols, err := Columns(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cols[0].IsVector() {
+		t.Errorf("expected a VECTOR column, got %s\n", cols[0].MysqlType())
+	}
+}
+
+func TestExtendedTypeName(t *testing.T) {
+	if name, ok := (mysqlField{fieldType: fieldTypeString, flags: flagBinary, length: 16}).ExtendedTypeName(); ok || name != "" {
+		t.Errorf("expected no extended type name, got %q\n", name)
+	}
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var version string
+	if err = db.QueryRow("SELECT VERSION()").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(version, "MariaDB") {
+		t.Skip("INET6 and UUID require MariaDB, server is " + version)
+	}
+	rows, err := db.Query("SELECT CAST('::1' AS INET6), UUID()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	cols, err := Columns(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the driver doesn't request the extended metadata, the base types are reported
+	for _, col := range cols {
+		if name, ok := col.ExtendedTypeName(); ok || name != "" {
+			t.Errorf("expected no extended type name for %s, got %q\n", col.MysqlType(), name)
+		}
+	}
+}
+
+func TestStructType(t *testing.T) {
+	fields := []mysqlField{
+		{name: "id", fieldType: fieldTypeLong, flags: flagNotNULL},
+		{name: "user_name", fieldType: fieldTypeVarString},
+		{name: "id", fieldType: fieldTypeDouble, flags: flagNotNULL},
+	}
+	rows := fakeQuery(t, newFakeRows(false, fields, []driver.Value{[]byte("7"), nil, []byte("1.5")}))
+	defer rows.Close()
+	cols, err := Columns(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	structType, err := StructType(cols, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		name, tag string
+		goType    reflect.Type
+	}{
+		{"Id", "id", reflect.TypeOf(int32(0))},
+		{"UserName", "user_name", reflect.TypeOf(sql.NullString{})},
+		{"Id2", "id", reflect.TypeOf(float64(0))},
+	}
+	if structType.NumField() != len(expected) {
+		t.Fatalf("expected %d fields, got %d\n", len(expected), structType.NumField())
+	}
+	for i, e := range expected {
+		field := structType.Field(i)
+		if field.Name != e.name || field.Tag.Get("db") != e.tag || field.Type != e.goType {
+			t.Errorf("field %d: %s %v `%s` did not match expected %s %v `db:\"%s\"`\n",
+				i, field.Name, field.Type, field.Tag, e.name, e.goType, e.tag)
+		}
+	}
+	value := reflect.New(structType).Elem()
+	dest := make([]interface{}, value.NumField())
+	for i := range dest {
+		dest[i] = value.Field(i).Addr().Interface()
+	}
+	if !rows.Next() {
+		t.Fatal("could not scan from sql.Rows")
+	}
+	if err = rows.Scan(dest...); err != nil {
+		t.Fatal(err)
+	}
+	if value.Field(0).Int() != 7 || value.Field(1).Field(1).Bool() || value.Field(2).Float() != 1.5 {
+		t.Errorf("scanned values did not match: %#v\n", value.Interface())
+	}
+}
+
+func TestColumnCache(t *testing.T) {
+	fields := []mysqlField{{name: "id", fieldType: fieldTypeLong}}
+	inspections := 0
+	cache := ColumnCache{
+		inspect: func(rowOrRows interface{}) ([]Column, error) {
+			inspections++
+			return Columns(rowOrRows)
+		},
+	}
+	for i := 0; i < 3; i++ {
+		rows := fakeQuery(t, newFakeRows(false, fields))
+		cols, err := cache.Get("SELECT id", rows)
+		rows.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cols) != 1 || cols[0].Name() != "id" {
+			t.Errorf("unexpected columns %#v\n", cols)
+		}
+	}
+	if inspections != 1 {
+		t.Errorf("expected 1 inspection, got %d\n", inspections)
+	}
+	cache.Delete("SELECT id")
+	rows := fakeQuery(t, newFakeRows(false, fields))
+	defer rows.Close()
+	if _, err := cache.Get("SELECT id", rows); err != nil {
+		t.Fatal(err)
+	}
+	if inspections != 2 {
+		t.Errorf("expected a new inspection after Delete, got %d inspections\n", inspections)
+	}
+	if _, err := cache.Get("invalid", nil); err == nil {
+		t.Errorf("expected an error for nil\n")
+	}
+}
+
+func TestIsTimestamp(t *testing.T) {
+	timestamp := mysqlField{fieldType: fieldTypeTimestamp}
+	datetime := mysqlField{fieldType: fieldTypeDateTime}
+	if !timestamp.IsTimestamp() || !timestamp.IsTime() {
+		t.Errorf("TIMESTAMP must be a temporal timestamp type\n")
+	}
+	if datetime.IsTimestamp() || !datetime.IsTime() {
+		t.Errorf("DATETIME must be a temporal type but not a timestamp\n")
+	}
+}
+
+func TestColumnsOffline(t *testing.T) {
+	var (
+		i8, u8, i16, i32, u32, i64, u64 = int8(0), uint8(0), int16(0), int32(0), uint32(0), int64(0), uint64(0)
+		f32, f64, str, bytes            = float32(0), float64(0), "", []byte{}
+	)
+	tests := []struct {
+		field     mysqlField
+		mysqlType string
+		goValue   interface{}
+		category  TypeCategory
+	}{
+		{mysqlField{fieldType: fieldTypeTiny}, "TINYINT", i8, CategoryInteger},
+		{mysqlField{fieldType: fieldTypeTiny, flags: flagUnsigned}, "TINYINT", u8, CategoryInteger},
+		{mysqlField{fieldType: fieldTypeShort}, "SMALLINT", i16, CategoryInteger},
+		{mysqlField{fieldType: fieldTypeInt24}, "INT", i32, CategoryInteger},
+		{mysqlField{fieldType: fieldTypeLong, flags: flagUnsigned}, "INT", u32, CategoryInteger},
+		{mysqlField{fieldType: fieldTypeLongLong}, "BIGINT", i64, CategoryInteger},
+		{mysqlField{fieldType: fieldTypeLongLong, flags: flagUnsigned}, "BIGINT", u64, CategoryInteger},
+		{mysqlField{fieldType: fieldTypeFloat}, "FLOAT", f32, CategoryFloat},
+		{mysqlField{fieldType: fieldTypeDouble}, "DOUBLE", f64, CategoryFloat},
+		{mysqlField{fieldType: fieldTypeDecimal}, "DECIMAL", big.NewInt(0), CategoryDecimal},
+		{mysqlField{fieldType: fieldTypeNewDecimal}, "DECIMAL", big.NewInt(0), CategoryDecimal},
+		{mysqlField{fieldType: fieldTypeYear}, "YEAR", time.Time{}, CategoryTemporal},
+		{mysqlField{fieldType: fieldTypeDate}, "DATE", time.Time{}, CategoryTemporal},
+		{mysqlField{fieldType: fieldTypeNewDate}, "DATE", time.Time{}, CategoryTemporal},
+		{mysqlField{fieldType: fieldTypeTime}, "TIME", time.Time{}, CategoryTemporal},
+		{mysqlField{fieldType: fieldTypeTimestamp}, "TIMESTAMP", time.Time{}, CategoryTemporal},
+		{mysqlField{fieldType: fieldTypeDateTime}, "DATETIME", time.Time{}, CategoryTemporal},
+		{mysqlField{fieldType: fieldTypeNULL}, "NULL", nil, CategoryNull},
+		{mysqlField{fieldType: fieldTypeBit}, "BIT", []bool{}, CategoryBit},
+		{mysqlField{fieldType: fieldTypeVarChar}, "VARCHAR", str, CategoryText},
+		{mysqlField{fieldType: fieldTypeVarString}, "VARCHAR", str, CategoryText},
+		{mysqlField{fieldType: fieldTypeString}, "CHAR", str, CategoryText},
+		{mysqlField{fieldType: fieldTypeEnum}, "ENUM", nil, CategoryEnum},
+		{mysqlField{fieldType: fieldTypeSet}, "SET", []string{}, CategorySet},
+		{mysqlField{fieldType: fieldTypeTinyBLOB}, "TINY BLOB", bytes, CategoryBlob},
+		{mysqlField{fieldType: fieldTypeMediumBLOB}, "MEDIUM BLOB", bytes, CategoryBlob},
+		{mysqlField{fieldType: fieldTypeBLOB}, "BLOB", bytes, CategoryBlob},
+		{mysqlField{fieldType: fieldTypeLongBLOB}, "LONG BLOB", bytes, CategoryBlob},
+		{mysqlField{fieldType: fieldTypeGeometry}, "GEOMETRY", nil, CategoryGeometry},
+		{mysqlField{fieldType: fieldTypeJSON}, "JSON", bytes, CategoryJSON},
+		{mysqlField{fieldType: fieldTypeVector}, "VECTOR", bytes, CategoryVector},
+	}
+	fields := make([]mysqlField, len(tests))
+	for i, setup := range tests {
+		fields[i] = setup.field
+		fields[i].name = "col" + strconv.Itoa(i)
+	}
+	for _, binary := range []bool{false, true} {
+		rows := fakeQuery(t, newFakeRows(binary, fields))
+		defer rows.Close()
+		cols, err := Columns(rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cols) != len(tests) {
+			t.Fatalf("expected %d columns, got %d\n", len(tests), len(cols))
+		}
+		if isBinary, err := IsBinary(rows); err != nil || isBinary != binary {
+			t.Errorf("IsBinary returned %t, '%v', expected %t\n", isBinary, err, binary)
+		}
+		for i, col := range cols {
+			setup := tests[i]
+			if col.Name() != fields[i].name {
+				t.Errorf("column %d: name '%s' did not match expected '%s'\n", i, col.Name(), fields[i].name)
+			}
+			if col.MysqlType() != setup.mysqlType {
+				t.Errorf("column %d: SQL type '%s' did not match expected '%s'\n", i, col.MysqlType(), setup.mysqlType)
+			}
+			if col.Category() != setup.category {
+				t.Errorf("column %d: category %d did not match expected %d\n", i, col.Category(), setup.category)
+			}
+			refl, err := col.ReflectGoType()
+			if setup.goValue == nil {
+				if err == nil {
+					t.Errorf("column %d: expected an error in ReflectGoType for %s\n", i, setup.mysqlType)
+				}
+				continue
+			}
+			if expected := reflect.TypeOf(setup.goValue); err != nil || refl != expected {
+				t.Errorf("column %d: Go type '%v' did not match expected '%v', error '%v'\n", i, refl, expected, err)
+			}
+		}
+	}
+}
+
+func TestOrdinalPosition(t *testing.T) {
+	field := mysqlField{tableName: "t", name: "b", fieldType: fieldTypeLong}
+	if position, ok := field.OrdinalPosition(); ok || position != 0 {
+		t.Errorf("expected unknown position, got (%d, %t)\n", position, ok)
+	}
+}
+
+func TestLargerLayoutRefused(t *testing.T) {
+	// make sure the layout check passed
+	if _, err := ColumnsFromDriverRows(newFakeRows(false, nil)); err != ErrNoResultSet {
+		t.Fatal(err)
+	}
+	// mimic a driver with a larger mysqlRows
+	type mysqlRows struct {
+		mc      *mysqlConn
+		rs      resultSet
+		finish  func()
+		unknown [4]uint64
+	}
+	type textRows struct {
+		mysqlRows
+		driver.Rows
+	}
+	if _, err := ColumnsFromDriverRows(&textRows{}); err == nil {
+		t.Errorf("expected an error for a larger mysqlRows\n")
+	}
+	if initOffsets(reflect.TypeOf(&textRows{})) == nil {
+		t.Errorf("expected initOffsets to fail for a larger mysqlRows\n")
+	}
+	// same outer size and offsets because of padding, but a larger inner struct
+	smaller := func() reflect.Type {
+		type inner struct {
+			a int32
+		}
+		return reflect.TypeOf(struct {
+			c int64
+			s inner
+		}{})
+	}()
+	larger := func() reflect.Type {
+		type inner struct {
+			a int32
+			b int8
+		}
+		return reflect.TypeOf(struct {
+			c int64
+			s inner
+		}{})
+	}()
+	if canConvert(smaller, larger) {
+		t.Errorf("structs with differently sized fields must not be convertible\n")
+	}
+}
+
+func TestInitOffsetsFlags(t *testing.T) {
+	wideFlags := func() reflect.Type {
+		type mysqlField struct {
+			tableName string
+			name      string
+			length    uint32
+			flags     uint32
+			fieldType byte
+			decimals  byte
+			charSet   uint8
+		}
+		type resultSet struct {
+			columns     []mysqlField
+			columnNames []string
+			done        bool
+		}
+		type mysqlRows struct {
+			mc     *mysqlConn
+			rs     resultSet
+			finish func()
+		}
+		type textRows struct {
+			mysqlRows
+		}
+		return reflect.TypeOf(&textRows{})
+	}()
+	if err := initOffsets(wideFlags); err == nil || err.Error() != "unexpected type of mysqlField.flags" {
+		t.Errorf("expected initOffsets to reject uint32 flags, got %v\n", err)
+	}
+	renamedFlags := func() reflect.Type {
+		type clientFlag uint16
+		type mysqlField struct {
+			tableName string
+			name      string
+			length    uint32
+			flags     clientFlag
+			fieldType byte
+			decimals  byte
+			charSet   uint8
+		}
+		type resultSet struct {
+			columns     []mysqlField
+			columnNames []string
+			done        bool
+		}
+		type mysqlRows struct {
+			mc     *mysqlConn
+			rs     resultSet
+			finish func()
+		}
+		type textRows struct {
+			mysqlRows
+		}
+		return reflect.TypeOf(&textRows{})
+	}()
+	if err := initOffsets(renamedFlags); err != nil {
+		t.Errorf("expected initOffsets to accept a differently named uint16 flags type, got %v\n", err)
+	}
+}
+
+func TestCanConvertTrailingSize(t *testing.T) {
+	// identical field names, offsets and field sizes, but inner differs in alignment
+	aligned := func() reflect.Type {
+		type inner struct {
+			x int64
+		}
+		type outer struct {
+			a inner
+			b int8
+		}
+		return reflect.TypeOf(outer{})
+	}()
+	unaligned := func() reflect.Type {
+		type inner struct {
+			x [8]byte
+		}
+		type outer struct {
+			a inner
+			b int8
+		}
+		return reflect.TypeOf(outer{})
+	}()
+	if aligned.Size() == unaligned.Size() {
+		t.Fatal("test structs must differ in size")
+	}
+	if canConvert(aligned, unaligned) || canConvert(unaligned, aligned) {
+		t.Errorf("structs of different size must not be convertible\n")
+	}
+}
+
+func TestCanConvertDepth(t *testing.T) {
+	nested := func(depth int) reflect.Type {
+		t := reflect.TypeOf(int64(0))
+		for i := 0; i < depth; i++ {
+			t = reflect.StructOf([]reflect.StructField{{Name: "F", Type: t}})
+		}
+		return t
+	}
+	if shallow := nested(maxConvertDepth); !canConvert(shallow, shallow) {
+		t.Errorf("expected structs nested %d levels deep to be convertible\n", maxConvertDepth)
+	}
+	if deep := nested(10 * maxConvertDepth); canConvert(deep, deep) {
+		t.Errorf("expected structs nested deeper than %d levels to be rejected\n", maxConvertDepth)
+	}
+	if canConvert(nil, reflect.TypeOf(mysqlRows{})) || canConvert(reflect.TypeOf(mysqlRows{}), nil) {
+		t.Errorf("expected nil types to be rejected\n")
+	}
+}
+
+// fuzzType builds a type from data for FuzzCanConvert, arrays are only used near the top
+// so the size of the type stays small.
+func fuzzType(data []byte, depth int) (reflect.Type, []byte) {
+	if len(data) == 0 || depth > 200 {
+		return reflect.TypeOf(int32(0)), data
+	}
+	op, data := data[0], data[1:]
+	var elem reflect.Type
+	switch op % 12 {
+	case 0:
+		return reflect.TypeOf(int8(0)), data
+	case 1:
+		return reflect.TypeOf(int64(0)), data
+	case 2:
+		return reflect.TypeOf(""), data
+	case 3:
+		return reflect.TypeOf((*interface{})(nil)).Elem(), data
+	case 4:
+		return reflect.TypeOf(mysqlField{}), data
+	case 5:
+		elem, data = fuzzType(data, depth+1)
+		return reflect.PtrTo(elem), data
+	case 6:
+		elem, data = fuzzType(data, depth+1)
+		return reflect.SliceOf(elem), data
+	case 7:
+		elem, data = fuzzType(data, depth+1)
+		return reflect.MapOf(reflect.TypeOf(""), elem), data
+	case 8:
+		elem, data = fuzzType(data, depth+1)
+		return reflect.ChanOf(reflect.BothDir, elem), data
+	case 9:
+		elem, data = fuzzType(data, depth+1)
+		if depth > 4 {
+			return elem, data
+		}
+		return reflect.ArrayOf(int(op>>4)%3, elem), data
+	}
+	// anonymous struct with up to 4 fields
+	fields := make([]reflect.StructField, 1+int(op>>4)%4)
+	for i := range fields {
+		fields[i].Name = "F" + strconv.Itoa(i)
+		fields[i].Type, data = fuzzType(data, depth+1)
+	}
+	return reflect.StructOf(fields), data
+}
+
+func FuzzCanConvert(f *testing.F) {
+	f.Add([]byte{10, 1, 2}, []byte{10, 1, 2})
+	f.Add([]byte{11, 5, 10, 0, 4}, []byte{11, 5, 10, 0, 3})
+	f.Add([]byte{26, 9, 10, 2, 7, 10, 1}, []byte{10, 9, 10, 2})
+	f.Add(bytes.Repeat([]byte{10}, 300), bytes.Repeat([]byte{10}, 300))
+	f.Fuzz(func(t *testing.T, a, b []byte) {
+		from, _ := fuzzType(a, 0)
+		to, _ := fuzzType(b, 0)
+		canConvert(from, to)
+		canConvert(to, from)
+		if from.Kind() == reflect.Struct && canConvert(from, from) != (structDepth(from) <= maxConvertDepth) {
+			t.Errorf("canConvert(%v, itself) must only fail for deeply nested structs\n", from)
+		}
+	})
+}
+
+// structDepth returns the nesting depth of anonymous structs in t like canConvert counts it.
+func structDepth(t reflect.Type) int {
+	max := 0
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i).Type
+		for ft.Kind() == reflect.Array || ft.Kind() == reflect.Chan || ft.Kind() == reflect.Map ||
+			ft.Kind() == reflect.Ptr || ft.Kind() == reflect.Slice {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct && ft.Name() == "" {
+			if d := structDepth(ft) + 1; d > max {
+				max = d
+			}
+		}
+	}
+	return max
+}
+
+func TestColumnsAndProtocol(t *testing.T) {
+	fields := []mysqlField{{name: "a", fieldType: fieldTypeLongLong}}
+	for _, binary := range []bool{false, true} {
+		rows := fakeQuery(t, newFakeRows(binary, fields))
+		defer rows.Close()
+		cols, isBinary, err := ColumnsAndProtocol(rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isBinary != binary {
+			t.Errorf("expected binary protocol %t, got %t\n", binary, isBinary)
+		}
+		if len(cols) != 1 || cols[0].Name() != "a" {
+			t.Errorf("unexpected columns %#v\n", cols)
+		}
+	}
+	if _, _, err := ColumnsAndProtocol(nil); err == nil {
+		t.Errorf("expected an error for nil\n")
+	}
+}
+
+func TestReflectSqlTypeColumns(t *testing.T) {
+	cols := []Column{
+		mysqlField{name: "id", fieldType: fieldTypeLong, flags: flagNotNULL},
+		mysqlField{name: "joined", fieldType: fieldTypeVarString, flags: flagNotNULL},
+	}
+	types, err := ReflectSqlTypeColumns(cols, []string{"joined"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []reflect.Type{reflect.TypeOf(int32(0)), reflect.TypeOf(sql.NullString{})}
+	if !reflect.DeepEqual(types, expected) {
+		t.Errorf("types %v did not match expected %v\n", types, expected)
+	}
+}
+
+func TestMaxLength(t *testing.T) {
+	tests := []struct {
+		field  mysqlField
+		length int64
+		ok     bool
+	}{
+		// VARCHAR(255) CHARACTER SET latin1
+		{field: mysqlField{fieldType: fieldTypeVarString, length: 255}, length: 255, ok: true},
+		// TEXT
+		{field: mysqlField{fieldType: fieldTypeBLOB, length: 65535}, length: 65535, ok: true},
+		// INT
+		{field: mysqlField{fieldType: fieldTypeLong, length: 11}},
+	}
+	for _, setup := range tests {
+		if length, ok := setup.field.MaxLength(); length != setup.length || ok != setup.ok {
+			t.Errorf("expected (%d, %t) for %s, got (%d, %t)\n",
+				setup.length, setup.ok, setup.field.MysqlType(), length, ok)
+		}
+	}
+}
+
+// openTestSchema opens a single connection using the database sqlinternals_test,
+// tables in it are visible in information_schema.
+func openTestSchema(t *testing.T) *sql.DB {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	for _, stmt := range []string{
+		"CREATE DATABASE IF NOT EXISTS sqlinternals_test",
+		"USE sqlinternals_test",
+	} {
+		if _, err = db.Exec(stmt); err != nil {
+			db.Close()
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+// schemaConn pins a connection of db for the lookups in information_schema.
+func schemaConn(t *testing.T, db *sql.DB) *sql.Conn {
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func TestIsGenerated(t *testing.T) {
+	db := openTestSchema(t)
+	defer db.Close()
+	for _, stmt := range []string{
+		"DROP TABLE IF EXISTS generated",
+		"CREATE TABLE generated (a INT, b INT AS (a * 2) VIRTUAL)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer db.Exec("DROP TABLE generated")
+	rows, err := db.Query("SELECT a, b, a + 1 FROM generated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cols, err := Columns(rows)
+	rows.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := schemaConn(t, db)
+	defer conn.Close()
+	for i, expected := range []bool{false, true, false} {
+		generated, expression, err := cols[i].IsGenerated(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if generated != expected || generated != (expression != "") {
+			t.Errorf("column %d: expected generated %t, got %t with expression '%s'\n",
+				i, expected, generated, expression)
+		}
+	}
+}
+
+func TestColumnDefault(t *testing.T) {
+	db := openTestSchema(t)
+	defer db.Close()
+	for _, stmt := range []string{
+		"DROP TABLE IF EXISTS defaults",
+		"CREATE TABLE defaults (created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, name VARCHAR(10) NOT NULL)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer db.Exec("DROP TABLE defaults")
+	rows, err := db.Query("SELECT created, name, 1 FROM defaults")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cols, err := Columns(rows)
+	rows.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := schemaConn(t, db)
+	defer conn.Close()
+	// MariaDB reports current_timestamp()
+	if value, ok, err := cols[0].ColumnDefault(conn); err != nil || !ok ||
+		!strings.HasPrefix(strings.ToUpper(value), "CURRENT_TIMESTAMP") {
+		t.Errorf("expected the default CURRENT_TIMESTAMP, got (%q, %t, %v)\n", value, ok, err)
+	}
+	for _, col := range cols[1:] {
+		if value, ok, err := col.ColumnDefault(conn); err != nil || ok || value != "" {
+			t.Errorf("expected no default for %s, got (%q, %t, %v)\n", col.Name(), value, ok, err)
+		}
+	}
+}
+
+func TestSRID(t *testing.T) {
+	db := openTestSchema(t)
+	defer db.Close()
+	for _, stmt := range []string{
+		"DROP TABLE IF EXISTS srid",
+		"CREATE TABLE srid (id INT, g POINT NOT NULL SRID 4326, h GEOMETRY)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer db.Exec("DROP TABLE srid")
+	rows, err := db.Query("SELECT id, g, h FROM srid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cols, err := Columns(rows)
+	rows.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		srid        uint32
+		ok          bool
+		declaration string
+	}{
+		{0, false, "INT"},
+		{4326, true, "GEOMETRY NOT NULL SRID 4326"},
+		{0, false, "GEOMETRY"},
+	}
+	conn := schemaConn(t, db)
+	defer conn.Close()
+	for i, test := range tests {
+		srid, ok, err := cols[i].SRID(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if srid != test.srid || ok != test.ok {
+			t.Errorf("column %d: expected SRID (%d, %t), got (%d, %t)\n", i, test.srid, test.ok, srid, ok)
+		}
+		if decl, err := cols[i].MysqlDeclarationWithSRID(conn); err != nil || decl != test.declaration {
+			t.Errorf("column %d: expected declaration %q, got %q (%v)\n", i, test.declaration, decl, err)
+		}
+	}
+}
+
+func TestSchemaLookupsWithoutConn(t *testing.T) {
+	f := mysqlField{tableName: "t", name: "g", fieldType: fieldTypeGeometry}
+	if _, _, err := f.IsGenerated(nil); err != errNilConn {
+		t.Errorf("IsGenerated: expected errNilConn, got %v\n", err)
+	}
+	if _, _, err := f.ColumnDefault(nil); err != errNilConn {
+		t.Errorf("ColumnDefault: expected errNilConn, got %v\n", err)
+	}
+	if _, _, err := f.SRID(nil); err != errNilConn {
+		t.Errorf("SRID: expected errNilConn, got %v\n", err)
+	}
+	if _, err := f.MysqlDeclarationWithSRID(nil); err != errNilConn {
+		t.Errorf("MysqlDeclarationWithSRID: expected errNilConn, got %v\n", err)
+	}
+}
+
+func TestRegisterLayout(t *testing.T) {
+	fields := []mysqlField{
+		{name: "id", fieldType: fieldTypeLong, flags: flagNotNULL | flagPriKey},
+		{name: "doc", fieldType: fieldTypeJSON},
+	}
+	if _, err := ColumnsFromDriverRows(&xRows{fields: fields}); err == nil {
+		t.Fatal("expected an error for rows without a registered layout")
+	}
+	validated := 0
+	RegisterLayout("xRows", func(rowsType reflect.Type) (Layout, error) {
+		validated++
+		if _, ok := rowsType.Elem().FieldByName("fields"); !ok {
+			return Layout{}, mysqlError("no fields")
+		}
+		return Layout{
+			Columns: func(rows driver.Rows) []Column {
+				fields := rows.(*xRows).fields
+				cols := make([]Column, len(fields))
+				for i, f := range fields {
+					cols[i] = f
+				}
+				return cols
+			},
+			IsBinary: func(driver.Rows) bool { return true },
+		}, nil
+	})
+	for i := 0; i < 2; i++ {
+		rows := fakeQuery(t, &xRows{fields: fields})
+		defer rows.Close()
+		cols, isBinary, err := ColumnsAndProtocol(rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !isBinary {
+			t.Errorf("expected the binary protocol from the registered layout\n")
+		}
+		if len(cols) != 2 || cols[0].Name() != "id" || !cols[0].IsPrimaryKey() || cols[1].Category() != CategoryJSON {
+			t.Errorf("unexpected columns %#v\n", cols)
+		}
+	}
+	if validated != 1 {
+		t.Errorf("expected the layout to be validated once, got %d\n", validated)
+	}
+}
+
+func TestReflectSqlTypeDecimalBit(t *testing.T) {
+	decimal := mysqlField{fieldType: fieldTypeNewDecimal, length: 12, decimals: 2}
+	if refl, err := decimal.ReflectSqlType(false); err != nil || refl != reflect.TypeOf(sql.NullString{}) {
+		t.Errorf("DECIMAL(10,2): type '%v' did not match expected 'sql.NullString', error '%v'\n", refl, err)
+	}
+	bit := mysqlField{fieldType: fieldTypeBit, length: 8, flags: flagUnsigned}
+	if refl, err := bit.ReflectSqlType(false); err != nil || refl != reflect.TypeOf([]byte{}) {
+		t.Errorf("BIT(8): type '%v' did not match expected '[]byte', error '%v'\n", refl, err)
+	}
+	// NOT NULL columns keep their Go type
+	decimal.flags = flagNotNULL
+	if refl, err := decimal.ReflectSqlType(false); err != nil || refl != reflect.TypeOf(big.NewInt(0)) {
+		t.Errorf("DECIMAL(10,2) NOT NULL: type '%v' did not match expected '*big.Int', error '%v'\n", refl, err)
+	}
+	if refl, err := decimal.ReflectSqlType(true); err != nil || refl != reflect.TypeOf(sql.NullString{}) {
+		t.Errorf("forced nullable DECIMAL(10,2): type '%v' did not match expected 'sql.NullString', error '%v'\n", refl, err)
+	}
+}
+
+func TestReflectJSONType(t *testing.T) {
+	tests := []struct {
+		decl  string
+		field mysqlField
+		refl  reflect.Type
+	}{
+		{"DECIMAL(10,2) NOT NULL", mysqlField{fieldType: fieldTypeNewDecimal, length: 12, decimals: 2, flags: flagNotNULL}, reflect.TypeOf(json.Number(""))},
+		{"DECIMAL(10,2)", mysqlField{fieldType: fieldTypeNewDecimal, length: 12, decimals: 2}, reflect.TypeOf(new(json.Number))},
+		{"BIGINT UNSIGNED NOT NULL", mysqlField{fieldType: fieldTypeLongLong, length: 20, flags: flagUnsigned | flagNotNULL}, reflect.TypeOf("")},
+		{"BIGINT UNSIGNED", mysqlField{fieldType: fieldTypeLongLong, length: 20, flags: flagUnsigned}, reflect.TypeOf(new(string))},
+		{"INT UNSIGNED NOT NULL", mysqlField{fieldType: fieldTypeLong, length: 10, flags: flagUnsigned | flagNotNULL}, reflect.TypeOf(uint32(0))},
+		{"DATETIME NOT NULL", mysqlField{fieldType: fieldTypeDateTime, length: 19, flags: flagNotNULL}, reflect.TypeOf(time.Time{})},
+		{"BIT(1) NOT NULL", mysqlField{fieldType: fieldTypeBit, length: 1, flags: flagUnsigned | flagNotNULL}, reflect.TypeOf(false)},
+		{"BIT(64) NOT NULL", mysqlField{fieldType: fieldTypeBit, length: 64, flags: flagUnsigned | flagNotNULL}, reflect.TypeOf("")},
+		{"BLOB", mysqlField{fieldType: fieldTypeBLOB, length: 65535, flags: flagBinary}, reflect.TypeOf([]byte{})},
+		{"JSON", mysqlField{fieldType: fieldTypeJSON, length: 4294967295, flags: flagBinary}, reflect.TypeOf(json.RawMessage{})},
+	}
+	for _, test := range tests {
+		if refl := test.field.ReflectJSONType(); refl != test.refl {
+			t.Errorf("%s: type '%v' did not match expected '%v'\n", test.decl, refl, test.refl)
+		}
+	}
+}
+
+func TestDiffColumns(t *testing.T) {
+	toColumns := func(fields ...mysqlField) []Column {
+		cols := make([]Column, len(fields))
+		for i, f := range fields {
+			cols[i] = f
+		}
+		return cols
+	}
+	id := mysqlField{name: "id", fieldType: fieldTypeLong, flags: flagNotNULL | flagPriKey}
+	name := mysqlField{name: "name", fieldType: fieldTypeVarString}
+	created := mysqlField{name: "created", fieldType: fieldTypeDateTime}
+	bigID := id
+	bigID.fieldType = fieldTypeLongLong
+	title := name
+	title.name = "title"
+	requiredName := name
+	requiredName.flags = flagNotNULL
+	tests := []struct {
+		name     string
+		old, new []Column
+		kinds    []ChangeKind
+	}{
+		{"unchanged", toColumns(id, name), toColumns(id, name), nil},
+		{"add", toColumns(id), toColumns(id, name), []ChangeKind{ColumnAdded}},
+		{"drop", toColumns(id, name, created), toColumns(id, created), []ChangeKind{ColumnRemoved}},
+		{"retype", toColumns(id, name), toColumns(bigID, name), []ChangeKind{ColumnRetyped}},
+		{"nullability", toColumns(id, name), toColumns(id, requiredName), []ChangeKind{ColumnNullabilityChanged}},
+		{"rename", toColumns(id, name), toColumns(id, title), []ChangeKind{ColumnRenamed}},
+		{"replace", toColumns(id, name), toColumns(id, created), []ChangeKind{ColumnAdded, ColumnRemoved}},
+	}
+	for _, test := range tests {
+		changes := DiffColumns(test.old, test.new)
+		if len(changes) != len(test.kinds) {
+			t.Errorf("%s: expected %d changes, got %#v\n", test.name, len(test.kinds), changes)
+			continue
+		}
+		for i, change := range changes {
+			if change.Kind != test.kinds[i] {
+				t.Errorf("%s: expected change %d to be %d, got %d\n", test.name, i, test.kinds[i], change.Kind)
+			}
+			if (change.Old == nil) != (change.Kind == ColumnAdded) || (change.New == nil) != (change.Kind == ColumnRemoved) {
+				t.Errorf("%s: unexpected columns in change %#v\n", test.name, change)
+			}
+		}
+	}
+}
+
+func TestDriverValueKind(t *testing.T) {
+	tests := []struct {
+		field             mysqlField
+		binary, parseTime bool
+		kind              reflect.Kind
+	}{
+		{mysqlField{fieldType: fieldTypeLong}, true, false, reflect.Int64},
+		{mysqlField{fieldType: fieldTypeLong}, false, false, reflect.Slice},
+		{mysqlField{fieldType: fieldTypeTiny, flags: flagUnsigned}, true, false, reflect.Int64},
+		{mysqlField{fieldType: fieldTypeFloat}, true, false, reflect.Float32},
+		{mysqlField{fieldType: fieldTypeDouble}, true, false, reflect.Float64},
+		{mysqlField{fieldType: fieldTypeVarString}, true, false, reflect.Slice},
+		{mysqlField{fieldType: fieldTypeVarString}, false, false, reflect.Slice},
+		{mysqlField{fieldType: fieldTypeNewDecimal}, true, false, reflect.Slice},
+		{mysqlField{fieldType: fieldTypeDateTime}, true, true, reflect.Struct},
+		{mysqlField{fieldType: fieldTypeDateTime}, false, true, reflect.Struct},
+		{mysqlField{fieldType: fieldTypeDateTime}, true, false, reflect.Slice},
+		{mysqlField{fieldType: fieldTypeTime}, true, true, reflect.Slice},
+		{mysqlField{fieldType: fieldTypeNULL}, true, false, reflect.Invalid},
+		{mysqlField{fieldType: 0xe0}, false, false, reflect.Invalid},
+	}
+	for _, test := range tests {
+		kind := test.field.DriverValueKind(test.binary, test.parseTime)
+		if kind != test.kind {
+			t.Errorf("%s (binary %t, parseTime %t): expected %v, got %v\n",
+				test.field.MysqlType(), test.binary, test.parseTime, test.kind, kind)
+		}
+	}
+}
+
+func TestColumnsUnchecked(t *testing.T) {
+	// a type no other test validates
+	type textRows struct {
+		mysqlRows
+		fakeValues
+	}
+	fields := []mysqlField{{name: "a", fieldType: fieldTypeLongLong}}
+	rows := fakeQuery(t, &textRows{mysqlRows: mysqlRows{rs: resultSet{columns: fields}}})
+	defer rows.Close()
+	var colsErr *ColumnsError
+	if _, err := ColumnsUnchecked(rows); !errors.As(err, &colsErr) || colsErr.Stage != StageNotValidated {
+		t.Errorf("expected StageNotValidated before the layout is validated, got %v\n", err)
+	}
+	if _, err := Columns(rows); err != nil {
+		t.Fatal(err)
+	}
+	cols, err := ColumnsUnchecked(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cols) != 1 || cols[0].Name() != "a" {
+		t.Errorf("unexpected columns %#v\n", cols)
+	}
+	if _, err := ColumnsUnchecked(nil); !errors.As(err, &colsErr) || colsErr.Stage != StageNil {
+		t.Errorf("expected StageNil for nil, got %v\n", err)
+	}
+	other := fakeQuery(t, &uncountedRows{columns: []string{"a"}})
+	defer other.Close()
+	if _, err := ColumnsUnchecked(other); !errors.As(err, &colsErr) || colsErr.Stage != StageNotValidated {
+		t.Errorf("expected StageNotValidated for rows of other drivers, got %v\n", err)
+	}
+}
+
+func benchmarkColumns(b *testing.B, columns func(interface{}) ([]Column, error)) {
+	fields := []mysqlField{{name: "a", fieldType: fieldTypeLongLong}, {name: "b", fieldType: fieldTypeVarString}}
+	rows := fakeQuery(b, newFakeRows(false, fields))
+	defer rows.Close()
+	if _, err := Columns(rows); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := columns(rows); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkColumns(b *testing.B) {
+	b.ReportAllocs()
+	benchmarkColumns(b, Columns)
+}
+
+func BenchmarkColumnsUnchecked(b *testing.B) {
+	benchmarkColumns(b, ColumnsUnchecked)
+}
+
+func TestNameNoCopy(t *testing.T) {
+	fields := []mysqlField{
+		{name: "id", fieldType: fieldTypeLong},
+		{name: "name", fieldType: fieldTypeVarString},
+	}
+	rows := fakeQuery(t, newFakeRows(false, fields))
+	defer rows.Close()
+	cols, err := Columns(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, col := range cols {
+		name := col.Name()
+		if (*reflect.StringHeader)(unsafe.Pointer(&name)).Data != (*reflect.StringHeader)(unsafe.Pointer(&fields[i].name)).Data {
+			t.Errorf("expected Name to share the memory of the driver's field %q\n", fields[i].name)
+		}
+	}
+	index := map[string]int{"id": 0, "name": 1}
+	allocs := testing.AllocsPerRun(100, func() {
+		for i, col := range cols {
+			if index[col.Name()] != i {
+				t.Fatalf("unexpected index for %s\n", col.Name())
+			}
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("expected lookups by Name without allocations, got %v\n", allocs)
+	}
+}
+
+func BenchmarkColumnNameLookup(b *testing.B) {
+	fields := []mysqlField{
+		{name: "id", fieldType: fieldTypeLong},
+		{name: "name", fieldType: fieldTypeVarString},
+		{name: "created", fieldType: fieldTypeDateTime},
+	}
+	rows := fakeQuery(b, newFakeRows(false, fields))
+	defer rows.Close()
+	cols, err := Columns(rows)
+	if err != nil {
+		b.Fatal(err)
+	}
+	index := map[string]int{"id": 0, "name": 1, "created": 2}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, col := range cols {
+			_ = index[col.Name()]
+		}
+	}
+}
+
+func TestColumnsForCurrentResultSet(t *testing.T) {
+	first := []mysqlField{{name: "1", fieldType: fieldTypeLongLong, flags: flagNotNULL}}
+	second := []mysqlField{{name: "a", fieldType: fieldTypeVarString, flags: flagNotNULL}}
+	dRows := newFakeRows(false, first).(*textRows)
+	dRows.resultSets = [][]mysqlField{second}
+	rows := fakeQuery(t, dRows)
+	defer rows.Close()
+	for i, expected := range [][]mysqlField{first, second} {
+		if i > 0 && !rows.NextResultSet() {
+			t.Fatalf("missing result set %d\n", i)
+		}
+		cols, err := ColumnsForCurrentResultSet(rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cols) != 1 || cols[0].Name() != expected[0].name || cols[0].Category() != expected[0].Category() {
+			t.Errorf("result set %d: unexpected columns %#v\n", i, cols)
+		}
+	}
+	if _, err := ColumnsForCurrentResultSet(nil); err == nil {
+		t.Errorf("expected an error for nil\n")
+	}
+}
+
+func TestColumnsForCurrentResultSetMultiStatement(t *testing.T) {
+	multiDSN := dsn + "?multiStatements=true"
+	if strings.Contains(dsn, "?") {
+		multiDSN = dsn + "&multiStatements=true"
+	}
+	db, err := sql.Open("mysql", multiDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	rows, err := db.Query("SELECT 1; SELECT 'a'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for i, category := range []TypeCategory{CategoryInteger, CategoryText} {
+		if i > 0 && !rows.NextResultSet() {
+			t.Fatalf("missing result set %d: %v\n", i, rows.Err())
+		}
+		cols, err := ColumnsForCurrentResultSet(rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cols) != 1 || cols[0].Category() != category {
+			t.Errorf("result set %d: unexpected columns %#v\n", i, cols)
+		}
+	}
+}
+
+func TestKeyKind(t *testing.T) {
+	tests := []struct {
+		flags fieldFlag
+		kind  KeyKind
+	}{
+		{0, KeyNone},
+		{flagNotNULL | flagUnsigned, KeyNone},
+		{flagPriKey, KeyPrimary},
+		{flagUniqueKey, KeyUnique},
+		{flagMultipleKey, KeyMultiple},
+		{flagPriKey | flagUniqueKey, KeyPrimary},
+		{flagPriKey | flagMultipleKey, KeyPrimary},
+		{flagUniqueKey | flagMultipleKey, KeyUnique},
+		{flagPriKey | flagUniqueKey | flagMultipleKey, KeyPrimary},
+	}
+	for _, test := range tests {
+		field := mysqlField{fieldType: fieldTypeLong, flags: test.flags}
+		if kind := field.KeyKind(); kind != test.kind {
+			t.Errorf("flags %b: expected key kind %d, got %d\n", test.flags, test.kind, kind)
+		}
+		if isKeyPart := field.IsKeyPart(); isKeyPart != (test.kind != KeyNone) {
+			t.Errorf("flags %b: unexpected IsKeyPart %t\n", test.flags, isKeyPart)
+		}
+	}
+}
+
+func TestReflectGoTypeOrBytes(t *testing.T) {
+	bytesType := reflect.TypeOf([]byte{})
+	for _, field := range []mysqlField{
+		{fieldType: 0xe0},
+		{fieldType: fieldTypeEnum},
+		{fieldType: fieldTypeGeometry},
+	} {
+		if _, err := field.ReflectGoType(); err == nil {
+			t.Errorf("expected ReflectGoType to fail for type %#x\n", field.fieldType)
+		}
+		if refl := field.ReflectGoTypeOrBytes(); refl != bytesType {
+			t.Errorf("type %#x: expected '[]byte', got '%v'\n", field.fieldType, refl)
+		}
+	}
+	if refl := (mysqlField{fieldType: fieldTypeLong}).ReflectGoTypeOrBytes(); refl != reflect.TypeOf(int32(0)) {
+		t.Errorf("INT: expected 'int32', got '%v'\n", refl)
+	}
+}
+
+func TestColumnsViaDriverInterfaces(t *testing.T) {
+	rows := &typedRows{
+		uncountedRows: uncountedRows{columns: []string{"id", "price", "name", "data"}},
+		typeNames:     []string{"UNSIGNED BIGINT", "DECIMAL", "VARCHAR", "BLOB"},
+		nullable:      []bool{false, true, true, false},
+		lengths:       []int64{20, 10, 255, 65535},
+		scales:        []int64{0, 2, 0, 0},
+	}
+	cols, err := ColumnsViaDriverInterfaces(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cols) != 4 {
+		t.Fatalf("expected 4 columns, got %d\n", len(cols))
+	}
+	id, price, name, data := cols[0], cols[1], cols[2], cols[3]
+	if id.Name() != "id" || id.MysqlType() != "BIGINT" || !id.IsUnsigned() || !id.IsNotNull() {
+		t.Errorf("unexpected id column %#v\n", id)
+	}
+	if precision, scale, ok := price.DecimalSize(); !ok || precision != 10 || scale != 2 || price.IsNotNull() {
+		t.Errorf("expected nullable DECIMAL(10,2), got (%d,%d) %#v\n", precision, scale, price)
+	}
+	rows.typeNames[1] = "UNSIGNED DECIMAL"
+	if cols, err := ColumnsViaDriverInterfaces(rows); err != nil {
+		t.Error(err)
+	} else if precision, scale, ok := cols[1].DecimalSize(); !ok || precision != 10 || scale != 2 || !cols[1].IsUnsigned() {
+		t.Errorf("expected DECIMAL(10,2) UNSIGNED, got (%d,%d) %#v\n", precision, scale, cols[1])
+	}
+	rows.typeNames[1] = "DECIMAL"
+	if length, ok := name.MaxLength(); !ok || length != 255 || !name.IsText() {
+		t.Errorf("expected VARCHAR with length 255, got %d %#v\n", length, name)
+	}
+	if !data.IsBlob() || !data.IsBinary() {
+		t.Errorf("expected binary BLOB, got %#v\n", data)
+	}
+	rows.typeNames[3] = "TEXT"
+	cols, err = ColumnsViaDriverInterfaces(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := cols[3]; !text.IsText() || text.IsBlob() || text.IsBinary() || text.MysqlType() != "TEXT" {
+		t.Errorf("expected TEXT, got %s %#v\n", text.MysqlType(), text)
+	}
+	if refl, err := cols[3].ReflectGoType(); err != nil || refl != reflect.TypeOf("") {
+		t.Errorf("expected TEXT to map to string, got %v (%v)\n", refl, err)
+	}
+	rows.typeNames[3] = "UNKNOWN"
+	if _, err := ColumnsViaDriverInterfaces(rows); err == nil {
+		t.Errorf("expected an error for an unknown type name\n")
+	}
+	// rows without the interfaces use the unsafe cast
+	fields := []mysqlField{{name: "a", fieldType: fieldTypeLongLong}}
+	if cols, err := ColumnsViaDriverInterfaces(newFakeRows(false, fields)); err != nil || len(cols) != 1 {
+		t.Errorf("expected the fallback to return 1 column, got %#v, error %v\n", cols, err)
+	}
+	if _, err := ColumnsViaDriverInterfaces(&uncountedRows{}); err == nil {
+		t.Errorf("expected an error for rows of other drivers without the interfaces\n")
+	}
+}
+
+func TestIsCaseSensitive(t *testing.T) {
+	const (
+		utf8mb4GeneralCI = 45
+		utf8mb4Bin       = 46
+		binary           = 63
+		utf8Bin          = 83
+		utf8mb4UnicodeCI = 224
+		// utf8mb4_0900_as_cs (278) is reported as koi8u_general_ci
+		utf8mb4As0900CS = 278 - 256
+	)
+	tests := []struct {
+		field            mysqlField
+		sensitive, known bool
+	}{
+		{mysqlField{fieldType: fieldTypeVarString, charSet: utf8mb4UnicodeCI}, false, true},
+		{mysqlField{fieldType: fieldTypeVarString, charSet: utf8Bin}, true, true},
+		{mysqlField{fieldType: fieldTypeVarString, charSet: utf8mb4Bin, flags: flagBinary}, true, true},
+		{mysqlField{fieldType: fieldTypeBLOB, charSet: binary, flags: flagBinary}, true, true},
+		// the default collation is trusted, others may be aliased by collations above 255
+		{mysqlField{fieldType: fieldTypeVarString, charSet: utf8mb4GeneralCI}, false, true},
+		{mysqlField{fieldType: fieldTypeVarString, charSet: utf8mb4Bin}, false, false},
+		{mysqlField{fieldType: fieldTypeVarString, charSet: utf8mb4As0900CS}, false, false},
+		{mysqlField{fieldType: fieldTypeString, charSet: 0}, false, false},
+		{mysqlField{fieldType: fieldTypeLong, charSet: binary}, false, false},
+	}
+	for _, test := range tests {
+		sensitive, known := test.field.IsCaseSensitive()
+		if sensitive != test.sensitive || known != test.known {
+			name, _ := test.field.Collation()
+			t.Errorf("%s with collation %q: expected (%t, %t), got (%t, %t)\n",
+				test.field.MysqlType(), name, test.sensitive, test.known, sensitive, known)
+		}
+	}
+	if name, ok := (mysqlField{charSet: utf8mb4UnicodeCI}).Collation(); !ok || name != "utf8mb4_unicode_ci" {
+		t.Errorf("expected collation utf8mb4_unicode_ci, got %q\n", name)
+	}
+	if name, ok := (mysqlField{charSet: utf8mb4As0900CS}).Collation(); ok {
+		t.Errorf("expected an unknown collation, got %q\n", name)
+	}
+	// the servers without collations above 255
+	defer Restore(Snapshot())
+	TrustCollationIDs = true
+	for _, test := range []struct {
+		field            mysqlField
+		sensitive, known bool
+	}{
+		{mysqlField{fieldType: fieldTypeVarString, charSet: utf8mb4GeneralCI}, false, true},
+		{mysqlField{fieldType: fieldTypeVarString, charSet: utf8mb4Bin}, true, true},
+	} {
+		if sensitive, known := test.field.IsCaseSensitive(); sensitive != test.sensitive || known != test.known {
+			t.Errorf("collation %d with TrustCollationIDs: expected (%t, %t), got (%t, %t)\n",
+				test.field.charSet, test.sensitive, test.known, sensitive, known)
+		}
+	}
+	if name, ok := (mysqlField{charSet: utf8mb4GeneralCI}).Collation(); !ok || name != "utf8mb4_general_ci" {
+		t.Errorf("expected collation utf8mb4_general_ci with TrustCollationIDs, got %q\n", name)
+	}
+}
+
+func TestDump(t *testing.T) {
+	fields := []mysqlField{
+		{tableName: "t", name: "id", fieldType: fieldTypeLong, flags: flagNotNULL | flagPriKey, length: 11},
+		{tableName: "t", name: "title", fieldType: fieldTypeVarString, length: 1020, charSet: 45},
+	}
+	rows := fakeQuery(t, newFakeRows(false, fields))
+	defer rows.Close()
+	dump := Dump(rows)
+	for _, expected := range []string{`"id"`, "type=0x03", `"title"`, "type=0xfd", "charset=45"} {
+		if !strings.Contains(dump, expected) {
+			t.Errorf("expected %q in dump:\n%s", expected, dump)
+		}
+	}
+	if lines := strings.Count(dump, "\n"); lines != len(fields) {
+		t.Errorf("expected %d lines, got %d:\n%s", len(fields), lines, dump)
+	}
+	if Dump(nil) == "" {
+		t.Errorf("expected an error message for nil\n")
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	fields := []mysqlField{
+		{tableName: "t", name: "id", fieldType: fieldTypeLong, flags: flagNotNULL | flagPriKey | flagUnsigned | flagAutoIncrement},
+		{tableName: "t", name: "title", fieldType: fieldTypeVarString, length: 1020, charSet: 45, flags: flagMultipleKey},
+		{tableName: "t", name: "price", fieldType: fieldTypeNewDecimal, length: 12, decimals: 2, flags: flagNotNULL},
+	}
+	rows := fakeQuery(t, newFakeRows(false, fields))
+	defer rows.Close()
+	desc, err := Describe(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "" +
+		"+-------+--------------+----------------+------+-----+----------------+\n" +
+		"| Name  | Type         | Go Type        | Null | Key | Extra          |\n" +
+		"+-------+--------------+----------------+------+-----+----------------+\n" +
+		"| id    | INT UNSIGNED | uint32         | NO   | PRI | auto_increment |\n" +
+		"| title | VARCHAR      | sql.NullString | YES  | MUL |                |\n" +
+		"| price | DECIMAL      | *big.Int       | NO   |     |                |\n" +
+		"+-------+--------------+----------------+------+-----+----------------+\n"
+	if desc != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, desc)
+	}
+	if _, err := Describe(nil); err == nil {
+		t.Errorf("expected an error for nil\n")
+	}
+}
+
+func TestValidateStruct(t *testing.T) {
+	cols := []Column{
+		mysqlField{name: "id", fieldType: fieldTypeLong, flags: flagNotNULL | flagPriKey | flagUnsigned},
+		mysqlField{name: "user_name", fieldType: fieldTypeVarString, flags: flagNotNULL},
+		mysqlField{name: "score", fieldType: fieldTypeDouble},
+		mysqlField{name: "created", fieldType: fieldTypeDateTime},
+	}
+	type matching struct {
+		ID       uint64 `db:"id"`
+		UserName string
+		Score    sql.NullFloat64 `db:"score"`
+		Created  *time.Time      `db:"created"`
+		ignored  int
+	}
+	if err := ValidateStruct(cols, &matching{}); err != nil {
+		t.Errorf("expected a matching struct, got %v\n", err)
+	}
+	type mismatched struct {
+		ID       int16 `db:"id"`
+		UserName string
+		Score    sql.NullFloat64 `db:"score"`
+		Created  *time.Time      `db:"created"`
+	}
+	if err := ValidateStruct(cols, mismatched{}); err == nil || !strings.Contains(err.Error(), "field ID") {
+		t.Errorf("expected an error for field ID, got %v\n", err)
+	}
+	type notNullable struct {
+		ID       uint32 `db:"id"`
+		UserName string
+		Score    float64 `db:"score"`
+		Created  *time.Time
+	}
+	if err := ValidateStruct(cols, notNullable{}); err == nil || !strings.Contains(err.Error(), "field Score") {
+		t.Errorf("expected an error for field Score, got %v\n", err)
+	}
+	type missing struct {
+		ID       uint64 `db:"id"`
+		UserName string
+	}
+	if err := ValidateStruct(cols, missing{}); err == nil {
+		t.Errorf("expected an error for a struct with less fields\n")
+	}
+	if err := ValidateStruct(cols, 1); err == nil {
+		t.Errorf("expected an error for a non-struct\n")
+	}
+}
+
+func TestColumnsError(t *testing.T) {
+	foreign := fakeQuery(t, &uncountedRows{columns: []string{"a"}})
+	defer foreign.Close()
+	// mimic a driver with a different resultSet
+	type resultSet struct {
+		columns []mysqlField
+	}
+	type mysqlRows struct {
+		mc     *mysqlConn
+		rs     resultSet
+		finish func()
+	}
+	type textRows struct {
+		mysqlRows
+		driver.Rows
+	}
+	tests := []struct {
+		name  string
+		err   func() error
+		typ   string
+		stage InspectStage
+	}{
+		{"nil", func() error { _, err := Columns(nil); return err }, "<nil>", StageNil},
+		{"non-rows", func() error { _, err := Columns(42); return err }, "int", StageNotRows},
+		{"foreign driver", func() error { _, err := Columns(foreign); return err }, "*sql.Rows", StageNotMysql},
+		{"layout mismatch", func() error { _, err := ColumnsFromDriverRows(&textRows{}); return err }, "*mysqlinternals.textRows", StageLayoutMismatch},
+	}
+	for _, test := range tests {
+		var columnsErr *ColumnsError
+		if err := test.err(); !errors.As(err, &columnsErr) {
+			t.Errorf("%s: expected a *ColumnsError, got %#v\n", test.name, err)
+			continue
+		}
+		if columnsErr.Type != test.typ || columnsErr.Stage != test.stage {
+			t.Errorf("%s: expected type %s and stage %q, got %s and %q\n",
+				test.name, test.typ, test.stage, columnsErr.Type, columnsErr.Stage)
+		}
+	}
+}
+
+func TestLossyScanTo(t *testing.T) {
+	tests := []struct {
+		field mysqlField
+		dst   interface{}
+		lossy bool
+	}{
+		{mysqlField{fieldType: fieldTypeLongLong, flags: flagUnsigned}, int64(0), true},
+		{mysqlField{fieldType: fieldTypeLongLong, flags: flagUnsigned}, uint64(0), false},
+		{mysqlField{fieldType: fieldTypeLong}, int64(0), false},
+		{mysqlField{fieldType: fieldTypeLong}, int16(0), true},
+		{mysqlField{fieldType: fieldTypeLong}, float64(0), false},
+		{mysqlField{fieldType: fieldTypeLongLong}, float64(0), true},
+		{mysqlField{fieldType: fieldTypeLong}, sql.NullInt64{}, false},
+		{mysqlField{fieldType: fieldTypeLong}, new(int64), false},
+		{mysqlField{fieldType: fieldTypeDouble}, float32(0), true},
+		{mysqlField{fieldType: fieldTypeFloat}, float64(0), false},
+		{mysqlField{fieldType: fieldTypeNewDecimal, length: 12, decimals: 2}, float64(0), true},
+		{mysqlField{fieldType: fieldTypeNewDecimal, length: 12, decimals: 2}, "", false},
+		{mysqlField{fieldType: fieldTypeNewDecimal, length: 11}, int64(0), false},
+		{mysqlField{fieldType: fieldTypeNewDecimal, length: 11}, int32(0), true},
+		{mysqlField{fieldType: fieldTypeNewDecimal, length: 11, decimals: 2}, int64(0), true},
+		{mysqlField{fieldType: fieldTypeDateTime, decimals: 6}, time.Time{}, false},
+		{mysqlField{fieldType: fieldTypeDateTime, decimals: 6}, int64(0), true},
+		{mysqlField{fieldType: fieldTypeDateTime}, mysql.NullTime{}, false},
+		{mysqlField{fieldType: fieldTypeVarString}, []byte{}, false},
+		{mysqlField{fieldType: fieldTypeVarString}, int64(0), true},
+	}
+	for _, test := range tests {
+		dst := reflect.TypeOf(test.dst)
+		lossy, reason := test.field.LossyScanTo(dst)
+		if lossy != test.lossy || lossy != (reason != "") {
+			t.Errorf("%s (decimals %d) to %v: expected lossy %t, got %t with reason %q\n",
+				test.field.MysqlType(), test.field.decimals, dst, test.lossy, lossy, reason)
+		}
+	}
+}
+
+func TestColumnsBestEffort(t *testing.T) {
+	// mimic a driver with an additional field in resultSet
+	type resultSet struct {
+		columns     []mysqlField
+		columnNames []string
+		done        bool
+		affected    int64
+	}
+	type mysqlRows struct {
+		mc     *mysqlConn
+		rs     resultSet
+		finish func()
+	}
+	type textRows struct {
+		mysqlRows
+		driver.Rows
+	}
+	fields := []mysqlField{{name: "a", fieldType: fieldTypeLongLong}, {name: "b", fieldType: fieldTypeVarString}}
+	dRows := &textRows{
+		mysqlRows: mysqlRows{rs: resultSet{columns: fields}},
+		Rows:      &uncountedRows{columns: []string{"a", "b"}},
+	}
+	rows := fakeQuery(t, dRows)
+	defer rows.Close()
+	if _, err := Columns(rows); err == nil {
+		t.Fatal("expected Columns to fail for the mismatched layout")
+	}
+	cols, degraded, err := ColumnsBestEffort(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !degraded {
+		t.Errorf("expected a degraded read\n")
+	}
+	if len(cols) != 2 || cols[0].Name() != "a" || !cols[1].IsText() {
+		t.Errorf("unexpected columns %#v\n", cols)
+	}
+	renamed := fakeQuery(t, &textRows{
+		mysqlRows: mysqlRows{rs: resultSet{columns: fields}},
+		Rows:      &uncountedRows{columns: []string{"b", "a"}},
+	})
+	defer renamed.Close()
+	var colsErr *ColumnsError
+	if _, degraded, err := ColumnsBestEffort(renamed); !errors.As(err, &colsErr) ||
+		colsErr.Stage != StageColumnNameMismatch || degraded {
+		t.Errorf("expected a column name mismatch, got %v (degraded %t)\n", err, degraded)
+	}
+	empty := fakeQuery(t, &textRows{Rows: &uncountedRows{}})
+	defer empty.Close()
+	if _, degraded, err := ColumnsBestEffort(empty); err != ErrNoResultSet || degraded {
+		t.Errorf("expected ErrNoResultSet, got %v (degraded %t)\n", err, degraded)
+	}
+	valid := fakeQuery(t, newFakeRows(false, fields))
+	defer valid.Close()
+	if cols, degraded, err := ColumnsBestEffort(valid); err != nil || degraded || len(cols) != 2 {
+		t.Errorf("expected a validated read, got %#v, %t, %v\n", cols, degraded, err)
+	}
+	if _, _, err := ColumnsBestEffort(nil); err == nil {
+		t.Errorf("expected an error for nil\n")
+	}
+}
+
+func TestWireEncoding(t *testing.T) {
+	tests := []struct {
+		field    mysqlField
+		binary   bool
+		encoding WireEncoding
+	}{
+		{mysqlField{fieldType: fieldTypeLong}, true, WireFixedWidth},
+		{mysqlField{fieldType: fieldTypeLong}, false, WireLengthEncoded},
+		{mysqlField{fieldType: fieldTypeDouble}, true, WireFixedWidth},
+		{mysqlField{fieldType: fieldTypeVarString}, true, WireLengthEncoded},
+		{mysqlField{fieldType: fieldTypeNewDecimal}, true, WireLengthEncoded},
+		{mysqlField{fieldType: fieldTypeDateTime}, true, WireTemporal},
+		{mysqlField{fieldType: fieldTypeDateTime}, false, WireLengthEncoded},
+		{mysqlField{fieldType: fieldTypeNULL}, true, WireNone},
+		{mysqlField{fieldType: 0xe0}, true, WireUnknown},
+	}
+	for _, test := range tests {
+		if encoding := test.field.WireEncoding(test.binary); encoding != test.encoding {
+			t.Errorf("%s (binary %t): expected encoding %d, got %d\n",
+				test.field.MysqlType(), test.binary, test.encoding, encoding)
+		}
+	}
+}
+
+func TestCSVHeader(t *testing.T) {
+	// SELECT * FROM orders JOIN products ON orders.product = products.id
+	cols := []Column{
+		mysqlField{tableName: "orders", name: "id", fieldType: fieldTypeLong},
+		mysqlField{tableName: "orders", name: "product", fieldType: fieldTypeLong},
+		mysqlField{tableName: "products", name: "id", fieldType: fieldTypeLong},
+		mysqlField{tableName: "products", name: "price", fieldType: fieldTypeNewDecimal},
+	}
+	for withTypes, expected := range map[bool][]string{
+		false: {"id", "product", "id_2", "price"},
+		true:  {"id:INT", "product:INT", "id_2:INT", "price:DECIMAL"},
+	} {
+		if header := CSVHeader(cols, withTypes); !reflect.DeepEqual(header, expected) {
+			t.Errorf("expected header %q, got %q\n", expected, header)
+		}
+	}
+	// a generated name must not clash with a later column name
+	cols = []Column{
+		mysqlField{name: "id", fieldType: fieldTypeLong},
+		mysqlField{name: "id", fieldType: fieldTypeLong},
+		mysqlField{name: "id_2", fieldType: fieldTypeLong},
+		mysqlField{name: "id", fieldType: fieldTypeLong},
+	}
+	expected := []string{"id", "id_2", "id_2_2", "id_3"}
+	if header := CSVHeader(cols, false); !reflect.DeepEqual(header, expected) {
+		t.Errorf("expected header %q, got %q\n", expected, header)
+	}
+}
+
+func TestSourceTables(t *testing.T) {
+	// SELECT o.id, c.name, p.title, o.amount * p.price, c.id FROM orders o JOIN customers c JOIN products p
+	cols := []Column{
+		mysqlField{tableName: "o", name: "id", fieldType: fieldTypeLong},
+		mysqlField{tableName: "c", name: "name", fieldType: fieldTypeVarString},
+		mysqlField{tableName: "p", name: "title", fieldType: fieldTypeVarString},
+		mysqlField{name: "o.amount * p.price", fieldType: fieldTypeNewDecimal},
+		mysqlField{tableName: "c", name: "id", fieldType: fieldTypeLong},
+	}
+	expected := []string{"o", "c", "p"}
+	if tables := SourceTables(cols); !reflect.DeepEqual(tables, expected) {
+		t.Errorf("expected tables %q, got %q\n", expected, tables)
+	}
+	if tables := SourceTables(cols[3:4]); tables != nil {
+		t.Errorf("expected no tables for an expression, got %q\n", tables)
+	}
+}
+
+// layoutDiff describes the differences of the fields of two struct types
+func layoutDiff(mirror, actual reflect.Type) []string {
+	var diffs []string
+	if mirror.Size() != actual.Size() {
+		diffs = append(diffs, "size "+strconv.Itoa(int(mirror.Size()))+" != "+strconv.Itoa(int(actual.Size())))
+	}
+	for i := 0; i < mirror.NumField() || i < actual.NumField(); i++ {
+		var m, a string
+		if i < mirror.NumField() {
+			f := mirror.Field(i)
+			m = f.Name + " " + f.Type.Kind().String() + " @" + strconv.Itoa(int(f.Offset))
+		}
+		if i < actual.NumField() {
+			f := actual.Field(i)
+			a = f.Name + " " + f.Type.Kind().String() + " @" + strconv.Itoa(int(f.Offset))
+		}
+		if m != a {
+			diffs = append(diffs, "field "+strconv.Itoa(i)+": mirror '"+m+"', driver '"+a+"'")
+		}
+	}
+	return diffs
+}
+
+func TestDriverLayoutMatches(t *testing.T) {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	rows, err := db.Query("SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	dRows, err := sqlinternals.Inspect(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diffs := driverLayoutDiff(reflect.TypeOf(dRows)); len(diffs) > 0 {
+		t.Errorf("driver changed, update the mirrors:\n\t%s\n", strings.Join(diffs, "\n\t"))
+	}
+}
+
+// driverLayoutDiff walks from the driver.Rows of rowsType to their mysqlField
+// and compares each step with the mirrored types.
+func driverLayoutDiff(rowsType reflect.Type) []string {
+	var diffs []string
+	actual := rowsType.Elem()
+	for _, step := range []struct {
+		name   string
+		mirror reflect.Type
+	}{
+		{"mysqlRows", reflect.TypeOf(mysqlRows{})},
+		{"rs", reflect.TypeOf(resultSet{})},
+		{"columns", reflect.TypeOf(mysqlField{})},
+	} {
+		field, ok := actual.FieldByName(step.name)
+		if !ok {
+			return append(diffs, actual.String()+" has no field "+step.name)
+		}
+		actual = field.Type
+		if actual.Kind() == reflect.Slice {
+			actual = actual.Elem()
+		}
+		for _, diff := range layoutDiff(step.mirror, actual) {
+			diffs = append(diffs, step.name+": "+diff)
+		}
+	}
+	return diffs
+}
+
+func TestDriverLayoutDiffOffline(t *testing.T) {
+	for _, dRows := range []driver.Rows{newFakeRows(false, nil), newFakeRows(true, nil)} {
+		rowsType := reflect.TypeOf(dRows)
+		if diffs := driverLayoutDiff(rowsType); len(diffs) > 0 {
+			t.Errorf("the fake %v does not match the mirrors:\n\t%s\n", rowsType, strings.Join(diffs, "\n\t"))
+		}
+		if _, err := mysqlLayout(rowsType); err != nil {
+			t.Errorf("the layout of the fake %v was refused: %v\n", rowsType, err)
+		}
+	}
+	// a driver without mysqlField.charSet
+	type changedField struct {
+		tableName string
+		name      string
+		length    uint32
+		flags     fieldFlag
+		fieldType byte
+		decimals  byte
+	}
+	type changedRows struct {
+		mysqlRows struct {
+			mc *mysqlConn
+			rs struct {
+				columns     []changedField
+				columnNames []string
+				done        bool
+			}
+			finish func()
+		}
+	}
+	if diffs := driverLayoutDiff(reflect.TypeOf(&changedRows{})); len(diffs) == 0 {
+		t.Errorf("expected differences for a changed mysqlField\n")
+	}
+	if diffs := driverLayoutDiff(reflect.TypeOf(&uncountedRows{})); len(diffs) == 0 {
+		t.Errorf("expected differences for rows without mysqlRows\n")
+	}
+}
+
+func TestReadColumnInfo(t *testing.T) {
+	fields := []mysqlField{
+		{tableName: "t", name: "id", fieldType: fieldTypeLong, flags: flagNotNULL | flagPriKey, length: 11},
+		{tableName: "t", name: "price", fieldType: fieldTypeNewDecimal, length: 12, decimals: 2, charSet: 63},
+	}
+	rows := fakeQuery(t, newFakeRows(false, fields))
+	defer rows.Close()
+	infos := make([]ColumnInfo, 3)
+	n, err := ReadColumnInfo(infos, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(fields) {
+		t.Fatalf("expected %d columns, got %d\n", len(fields), n)
+	}
+	for i, f := range fields {
+		if col := infos[i].Column(); !reflect.DeepEqual(col, Column(f)) {
+			t.Errorf("column %d: expected %#v, got %#v\n", i, f, col)
+		}
+	}
+	if n, err := ReadColumnInfo(infos[:1], rows); err == nil || n != 1 {
+		t.Errorf("expected 1 column and an error for a short dst, got %d, %v\n", n, err)
+	}
+}
+
+func BenchmarkReadColumnInfo(b *testing.B) {
+	fields := []mysqlField{{name: "a", fieldType: fieldTypeLongLong}, {name: "b", fieldType: fieldTypeVarString}}
+	rows := fakeQuery(b, newFakeRows(false, fields))
+	defer rows.Close()
+	infos := make([]ColumnInfo, len(fields))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ReadColumnInfo(infos, rows); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestMysqlDeclarationWithCharset(t *testing.T) {
+	const (
+		utf8GeneralCI    = 33
+		utf8UnicodeCI    = 192
+		utf8mb4UnicodeCI = 224
+		utf8mb4Bin       = 46
+		binary           = 63
+	)
+	tests := []struct {
+		field mysqlField
+		decl  string
+	}{
+		{mysqlField{fieldType: fieldTypeVarString, charSet: utf8UnicodeCI, flags: flagNotNULL},
+			"VARCHAR(10) CHARACTER SET utf8mb3 COLLATE utf8mb3_unicode_ci NOT NULL"},
+		{mysqlField{fieldType: fieldTypeVarString, charSet: utf8mb4UnicodeCI},
+			"VARCHAR(10) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci"},
+		// the collations up to 67 may be aliased, only utf8mb4 is certain
+		{mysqlField{fieldType: fieldTypeString, charSet: utf8mb4Bin, flags: flagBinary},
+			"CHAR(10) BINARY CHARACTER SET utf8mb4"},
+		// except for the default collations
+		{mysqlField{fieldType: fieldTypeVarString, charSet: utf8GeneralCI},
+			"VARCHAR(10) CHARACTER SET utf8mb3 COLLATE utf8mb3_general_ci"},
+		{mysqlField{fieldType: fieldTypeVarString, charSet: binary, flags: flagBinary},
+			"VARCHAR(10) BINARY"},
+		{mysqlField{fieldType: fieldTypeVarString},
+			"VARCHAR(10)"},
+		{mysqlField{fieldType: fieldTypeLong, charSet: utf8mb4Bin},
+			"INT"},
+	}
+	for _, test := range tests {
+		var args []interface{}
+		if test.field.MysqlParameters() != ParamNone {
+			args = append(args, 10)
+		}
+		decl, err := test.field.MysqlDeclarationWithCharset(args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decl != test.decl {
+			t.Errorf("expected declaration '%s', got '%s'\n", test.decl, decl)
+		}
+	}
+	// MysqlDeclaration is unchanged
+	if decl, _ := tests[0].field.MysqlDeclaration(10); decl != "VARCHAR(10) NOT NULL" {
+		t.Errorf("expected declaration without charset, got '%s'\n", decl)
+	}
+}
+
+func TestFieldNamer(t *testing.T) {
+	for column, expected := range map[string]string{
+		"user_id":     "UserId",
+		"2fa_enabled": "X2faEnabled",
+		"select":      "Select",
+		"a-b c":       "ABC",
+	} {
+		if name := FieldNamer(column); name != expected {
+			t.Errorf("expected field name %s for %q, got %s\n", expected, column, name)
+		}
+	}
+	defer func(namer func(string) string) {
+		FieldNamer = namer
+	}(FieldNamer)
+	FieldNamer = func(columnName string) string {
+		return "F" + strings.ToUpper(columnName)
+	}
+	cols := []Column{mysqlField{name: "user_id", fieldType: fieldTypeLong, flags: flagNotNULL}}
+	structType, err := StructType(cols, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := structType.Field(0).Name; name != "FUSER_ID" {
+		t.Errorf("expected the custom field name FUSER_ID, got %s\n", name)
+	}
+	type custom struct {
+		FUSER_ID int32
+	}
+	if err := ValidateStruct(cols, custom{}); err != nil {
+		t.Errorf("expected ValidateStruct to use the custom names, got %v\n", err)
+	}
+	FieldNamer = strings.ToLower
+	if _, err := StructType(cols, false); err == nil {
+		t.Errorf("expected an error for unexported field names\n")
+	}
+}
+
+func TestMysqlTypeOnly(t *testing.T) {
+	tests := []struct {
+		field      mysqlField
+		args       []interface{}
+		decl, bare string
+	}{
+		{mysqlField{fieldType: fieldTypeLong, flags: flagUnsigned | flagNotNULL},
+			nil, "INT UNSIGNED NOT NULL", "INT"},
+		{mysqlField{fieldType: fieldTypeTiny, flags: flagUnsigned | flagZeroFill},
+			nil, "TINYINT UNSIGNED ZEROFILL", "TINYINT"},
+		{mysqlField{fieldType: fieldTypeVarString, flags: flagBinary | flagNotNULL},
+			[]interface{}{20}, "VARCHAR(20) BINARY NOT NULL", "VARCHAR(20)"},
+		{mysqlField{fieldType: fieldTypeNewDecimal, decimals: 2, flags: flagUnsigned},
+			[]interface{}{10}, "DECIMAL(10,2) UNSIGNED", "DECIMAL(10,2)"},
+	}
+	for _, test := range tests {
+		if decl, err := test.field.MysqlDeclaration(test.args...); err != nil || decl != test.decl {
+			t.Errorf("expected declaration '%s', got '%s', error '%v'\n", test.decl, decl, err)
+		}
+		if bare, err := test.field.MysqlTypeOnly(test.args...); err != nil || bare != test.bare {
+			t.Errorf("expected type '%s', got '%s', error '%v'\n", test.bare, bare, err)
+		}
+	}
+}
+
+func TestIsNull(t *testing.T) {
+	// SELECT NULL
+	field := mysqlField{name: "NULL", fieldType: fieldTypeNULL, flags: flagBinary}
+	if !field.IsNull() {
+		t.Errorf("expected IsNull for SELECT NULL\n")
+	}
+	predicates := map[string]func() bool{
+		"IsNumber":        field.IsNumber,
+		"IsInteger":       field.IsInteger,
+		"IsFloatingPoint": field.IsFloatingPoint,
+		"IsDecimal":       field.IsDecimal,
+		"IsText":          field.IsText,
+		"IsBlob":          field.IsBlob,
+		"IsTime":          field.IsTime,
+		"IsTimestamp":     field.IsTimestamp,
+		"IsVector":        field.IsVector,
+	}
+	for name, predicate := range predicates {
+		if predicate() {
+			t.Errorf("expected %s to be false for SELECT NULL\n", name)
+		}
+	}
+	if (mysqlField{fieldType: fieldTypeLong}).IsNull() {
+		t.Errorf("expected IsNull to be false for INT\n")
+	}
+}
+
+func TestNamedColumns(t *testing.T) {
+	fields := []mysqlField{
+		{name: "id", fieldType: fieldTypeLongLong, flags: flagNotNULL | flagPriKey},
+		{name: "name", fieldType: fieldTypeVarString},
+		{name: "created", fieldType: fieldTypeDateTime, flags: flagNotNULL},
+	}
+	rows := fakeQuery(t, newFakeRows(false, fields))
+	defer rows.Close()
+	names, cols, err := NamedColumns(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != len(fields) || len(cols) != len(fields) {
+		t.Fatalf("expected %d names and columns, got %d and %d\n", len(fields), len(names), len(cols))
+	}
+	for i, field := range fields {
+		if names[i] != field.name || cols[i].Name() != names[i] {
+			t.Errorf("column %d: name %q and column %q are not aligned with %q\n", i, names[i], cols[i].Name(), field.name)
+		}
+		if cols[i].MysqlType() != field.MysqlType() {
+			t.Errorf("column %d: expected type %s, got %s\n", i, field.MysqlType(), cols[i].MysqlType())
+		}
+	}
+	if _, _, err := NamedColumns(nil); err == nil {
+		t.Errorf("expected an error for nil\n")
+	}
+}
+
+func TestMysqlDeclarationFor(t *testing.T) {
+	tests := []struct {
+		field          mysqlField
+		args           []interface{}
+		legacy, modern string
+	}{
+		{mysqlField{fieldType: fieldTypeLong, flags: flagUnsigned | flagZeroFill | flagNotNULL},
+			[]interface{}{10}, "INT UNSIGNED ZEROFILL NOT NULL", "INT UNSIGNED NOT NULL"},
+		{mysqlField{fieldType: fieldTypeTiny, flags: flagUnsigned | flagZeroFill},
+			nil, "TINYINT UNSIGNED ZEROFILL", "TINYINT UNSIGNED"},
+		{mysqlField{fieldType: fieldTypeLongLong},
+			[]interface{}{20}, "BIGINT", "BIGINT"},
+		{mysqlField{fieldType: fieldTypeNewDecimal, decimals: 2},
+			[]interface{}{10}, "DECIMAL(10,2)", "DECIMAL(10,2)"},
+		{mysqlField{fieldType: fieldTypeVarString, flags: flagNotNULL},
+			[]interface{}{20}, "VARCHAR(20) NOT NULL", "VARCHAR(20) NOT NULL"},
+	}
+	for _, test := range tests {
+		if decl, err := test.field.MysqlDeclarationFor(DeclarationLegacy, test.args...); err != nil || decl != test.legacy {
+			t.Errorf("expected legacy declaration '%s', got '%s', error '%v'\n", test.legacy, decl, err)
+		}
+		if decl, err := test.field.MysqlDeclaration(test.args...); err != nil || decl != test.legacy {
+			t.Errorf("expected MysqlDeclaration to match legacy '%s', got '%s', error '%v'\n", test.legacy, decl, err)
+		}
+		if decl, err := test.field.MysqlDeclarationFor(DeclarationModern, test.args...); err != nil || decl != test.modern {
+			t.Errorf("expected modern declaration '%s', got '%s', error '%v'\n", test.modern, decl, err)
+		}
+	}
+}
+
+func TestEstimateRowBytes(t *testing.T) {
+	cols := fieldColumns([]mysqlField{
+		{name: "id", fieldType: fieldTypeLongLong, flags: flagNotNULL | flagUnsigned},
+		{name: "ratio", fieldType: fieldTypeDouble},
+		{name: "price", fieldType: fieldTypeNewDecimal, length: 12, decimals: 2},
+		{name: "created", fieldType: fieldTypeDateTime},
+		{name: "name", fieldType: fieldTypeVarString, length: 80},
+		{name: "body", fieldType: fieldTypeBLOB, length: 65535},
+	})
+	defer func(limit int64) { UnboundedColumnBytes = limit }(UnboundedColumnBytes)
+	UnboundedColumnBytes = 1000
+	// 8 + 8 + (10 + 2) + time.Time + 80 + 1000
+	expected := int64(8+8+12+80+1000) + int64(typeTime.Size())
+	if size := EstimateRowBytes(cols); size != expected {
+		t.Errorf("expected an estimate of %d bytes, got %d\n", expected, size)
+	}
+	UnboundedColumnBytes = 100000
+	if size := EstimateRowBytes(cols); size != expected-1000+65535 {
+		t.Errorf("expected the BLOB to count with its maximum length, got %d\n", size)
+	}
+	if size := EstimateRowBytes(nil); size != 0 {
+		t.Errorf("expected 0 bytes without columns, got %d\n", size)
+	}
+}
+
+// allFieldTypes lists each type code known to the package
+var allFieldTypes = []byte{
+	fieldTypeDecimal, fieldTypeTiny, fieldTypeShort, fieldTypeLong, fieldTypeFloat,
+	fieldTypeDouble, fieldTypeNULL, fieldTypeTimestamp, fieldTypeLongLong, fieldTypeInt24,
+	fieldTypeDate, fieldTypeTime, fieldTypeDateTime, fieldTypeYear, fieldTypeNewDate,
+	fieldTypeVarChar, fieldTypeBit, fieldTypeJSON, fieldTypeNewDecimal, fieldTypeEnum,
+	fieldTypeSet, fieldTypeTinyBLOB, fieldTypeMediumBLOB, fieldTypeLongBLOB, fieldTypeBLOB,
+	fieldTypeVarString, fieldTypeString, fieldTypeGeometry, fieldTypeVector,
+}
+
+// declarationArgs returns valid parameters for MysqlDeclaration of col
+func declarationArgs(col Column) []interface{} {
+	switch col.MysqlParameters() {
+	case ParamMayLength, ParamMustLength:
+		return []interface{}{10}
+	case ParamValues:
+		return []interface{}{"a", "b"}
+	}
+	return nil
+}
+
+func TestColumnMethods(t *testing.T) {
+	is := func(cond bool, problem string) string {
+		if cond {
+			return ""
+		}
+		return problem
+	}
+	checks := []struct {
+		method string
+		check  func(Column) string
+	}{
+		{"Name", func(c Column) string { return is(c.Name() == "c", "wrong name "+c.Name()) }},
+		{"TableName", func(c Column) string { return is(c.TableName() == "t", "wrong table "+c.TableName()) }},
+		{"MysqlType", func(c Column) string { return is(c.MysqlType() != "", "no type name") }},
+		{"Category", func(c Column) string { return is(c.Category() != CategoryUnknown, "unknown category") }},
+		{"IsNumber", func(c Column) string {
+			return is(c.IsNumber() == (c.IsInteger() || c.IsFloatingPoint() || c.IsDecimal()), "disagrees with the numeric predicates")
+		}},
+		{"IsInteger", func(c Column) string {
+			return is(c.IsInteger() == (c.Category() == CategoryInteger), "disagrees with Category")
+		}},
+		{"IsFloatingPoint", func(c Column) string {
+			return is(c.IsFloatingPoint() == (c.Category() == CategoryFloat), "disagrees with Category")
+		}},
+		{"IsDecimal", func(c Column) string {
+			return is(c.IsDecimal() == (c.Category() == CategoryDecimal), "disagrees with Category")
+		}},
+		{"IsText", func(c Column) string {
+			return is(c.IsText() == (c.Category() == CategoryText), "disagrees with Category")
+		}},
+		{"IsBlob", func(c Column) string {
+			return is(c.IsBlob() == (c.Category() == CategoryBlob), "disagrees with Category")
+		}},
+		{"IsTime", func(c Column) string {
+			return is(c.IsTime() == (c.Category() == CategoryTemporal), "disagrees with Category")
+		}},
+		{"IsTimestamp", func(c Column) string { return is(!c.IsTimestamp() || c.IsTime(), "set on a non-temporal type") }},
+		{"IsVector", func(c Column) string {
+			return is(c.IsVector() == (c.Category() == CategoryVector), "disagrees with Category")
+		}},
+		{"IsNull", func(c Column) string {
+			return is(c.IsNull() == (c.Category() == CategoryNull), "disagrees with Category")
+		}},
+		{"GeometryType", func(c Column) string {
+			name, ok := c.GeometryType()
+			return is(!ok && (name != "") == (c.Category() == CategoryGeometry), "unexpected name "+name)
+		}},
+		{"IsSigned", func(c Column) string { return is(c.IsSigned() == c.IsNumber(), "disagrees with IsNumber") }},
+		{"KeyKind", func(c Column) string { return is(c.KeyKind() == KeyNone && !c.IsKeyPart(), "reports a key") }},
+		{"MaxLength", func(c Column) string {
+			_, ok := c.MaxLength()
+			return is(ok == (c.IsText() || c.IsBlob()), "ok disagrees with IsText and IsBlob")
+		}},
+		{"DecimalSize", func(c Column) string {
+			_, _, ok := c.DecimalSize()
+			return is(ok == c.IsDecimal(), "ok disagrees with IsDecimal")
+		}},
+		{"MysqlParameters", func(c Column) string {
+			return is((c.MysqlParameters() == ParamUnknown) == c.IsNull(), "unexpected parameters")
+		}},
+		{"MysqlDeclaration", func(c Column) string {
+			decl, err := c.MysqlDeclaration(declarationArgs(c)...)
+			if c.IsNull() {
+				return is(err != nil, "no error for NULL")
+			}
+			return is(err == nil && strings.HasPrefix(decl, c.MysqlType()), fmt.Sprintf("got %q, error %v", decl, err))
+		}},
+		{"MysqlTypeOnly", func(c Column) string {
+			decl, _ := c.MysqlDeclaration(declarationArgs(c)...)
+			bare, _ := c.MysqlTypeOnly(declarationArgs(c)...)
+			return is(strings.HasPrefix(decl, bare), fmt.Sprintf("%q is no prefix of %q", bare, decl))
+		}},
+		{"MysqlDeclarationFor", func(c Column) string {
+			decl, _ := c.MysqlDeclaration(declarationArgs(c)...)
+			legacy, _ := c.MysqlDeclarationFor(DeclarationLegacy, declarationArgs(c)...)
+			return is(decl == legacy, fmt.Sprintf("legacy %q differs from %q", legacy, decl))
+		}},
+		{"SmallestGoIntType", func(c Column) string {
+			_, err := c.SmallestGoIntType()
+			return is((err == nil) == c.IsInteger(), fmt.Sprintf("unexpected error %v", err))
+		}},
+		{"ReflectGoTypeOrBytes", func(c Column) string {
+			goType, err := c.ReflectGoType()
+			orBytes := c.ReflectGoTypeOrBytes()
+			return is(orBytes != nil && (err != nil || goType == orBytes), fmt.Sprintf("got %v for %v", orBytes, goType))
+		}},
+		{"ReflectSqlType", func(c Column) string {
+			sqlType, err := c.ReflectSqlType(true)
+			return is(err != nil || sqlType != nil, "no type and no error")
+		}},
+		{"LossyScanTo", func(c Column) string {
+			lossy, reason := c.LossyScanTo(typeBytes)
+			return is(!lossy, "scanning into []byte is lossy: "+reason)
+		}},
+		{"ScanType", func(c Column) string { return is(c.ScanType() != nil, "no scan type") }},
+		{"DriverValueKind", func(c Column) string {
+			kind := c.DriverValueKind(false, false)
+			return is((kind == reflect.Invalid) == c.IsNull(), "unexpected kind "+kind.String())
+		}},
+		{"WireEncoding", func(c Column) string {
+			encoding := c.WireEncoding(false)
+			return is((encoding == WireNone) == c.IsNull() && encoding != WireUnknown, fmt.Sprintf("unexpected encoding %d", encoding))
+		}},
+		{"Validate", func(c Column) string { return is(c.Validate() == nil, fmt.Sprintf("problems %v", c.Validate())) }},
+	}
+	for _, fieldType := range allFieldTypes {
+		col := Column(mysqlField{tableName: "t", name: "c", length: 10, fieldType: fieldType})
+		for _, check := range checks {
+			if problem := check.check(col); problem != "" {
+				t.Errorf("%s of type code %#x: %s\n", check.method, fieldType, problem)
+			}
+		}
+	}
+}
+
+func TestFilterColumns(t *testing.T) {
+	cols := fieldColumns([]mysqlField{
+		{name: "id", fieldType: fieldTypeLong, flags: flagNotNULL | flagPriKey},
+		{name: "name", fieldType: fieldTypeVarString},
+		{name: "price", fieldType: fieldTypeNewDecimal, length: 10, decimals: 2},
+		{name: "created", fieldType: fieldTypeDateTime},
+		{name: "ratio", fieldType: fieldTypeDouble},
+	})
+	numbers := FilterColumns(cols, Column.IsNumber)
+	var names []string
+	for _, col := range numbers {
+		names = append(names, col.Name())
+	}
+	if strings.Join(names, ",") != "id,price,ratio" {
+		t.Errorf("expected the numeric columns id, price and ratio, got %v\n", names)
+	}
+	if keys := FilterColumns(cols, Column.IsKeyPart); len(keys) != 1 || keys[0].Name() != "id" {
+		t.Errorf("expected the key column id, got %v\n", keys)
+	}
+	if none := FilterColumns(cols, Column.IsBlob); none != nil {
+		t.Errorf("expected no columns, got %v\n", none)
+	}
+}
+
+func TestFloatDeclaration(t *testing.T) {
+	tests := []struct {
+		field mysqlField
+		args  []interface{}
+		decl  string
+	}{
+		// FLOAT(7,4)
+		{mysqlField{fieldType: fieldTypeFloat, length: 7, decimals: 4}, nil, "FLOAT(7,4)"},
+		{mysqlField{fieldType: fieldTypeFloat, length: 7, decimals: 4}, []interface{}{9}, "FLOAT(9,4)"},
+		// FLOAT
+		{mysqlField{fieldType: fieldTypeFloat, length: 12, decimals: decimalsNotFixed}, nil, "FLOAT"},
+		{mysqlField{fieldType: fieldTypeFloat, length: 12, decimals: decimalsNotFixed}, []interface{}{9}, "FLOAT(9)"},
+		{mysqlField{fieldType: fieldTypeDouble, length: 22, decimals: decimalsNotFixed, flags: flagNotNULL}, nil, "DOUBLE NOT NULL"},
+		{mysqlField{fieldType: fieldTypeDouble, length: 16, decimals: 2, flags: flagUnsigned}, nil, "DOUBLE(16,2) UNSIGNED"},
+		// DECIMAL is unaffected
+		{mysqlField{fieldType: fieldTypeNewDecimal, length: 12, decimals: 2}, nil, "DECIMAL"},
+	}
+	for _, test := range tests {
+		if decl, err := test.field.MysqlDeclaration(test.args...); err != nil || decl != test.decl {
+			t.Errorf("expected declaration '%s', got '%s', error '%v'\n", test.decl, decl, err)
+		}
+	}
+	double := mysqlField{fieldType: fieldTypeDouble, length: 22, decimals: decimalsNotFixed}
+	if decl, err := double.MysqlDeclaration(9); err == nil {
+		t.Errorf("expected an error for DOUBLE with a length but no scale, got '%s'\n", decl)
+	}
+}
+
+func TestParameters(t *testing.T) {
+	if _, err := Parameters(nil); err == nil || err == ErrNoParameterMetadata {
+		t.Errorf("expected an error for nil, got %v\n", err)
+	}
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	stmt, err := db.Prepare("SELECT ? + ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	if params, err := Parameters(stmt); err != ErrNoParameterMetadata || params != nil {
+		t.Errorf("expected ErrNoParameterMetadata, got %v, %v\n", params, err)
+	}
+}
+
+func TestArrowFields(t *testing.T) {
+	cols := fieldColumns([]mysqlField{
+		{name: "id", fieldType: fieldTypeLongLong, flags: flagNotNULL | flagUnsigned | flagPriKey},
+		{name: "qty", fieldType: fieldTypeShort},
+		{name: "ratio", fieldType: fieldTypeFloat, decimals: decimalsNotFixed},
+		{name: "score", fieldType: fieldTypeDouble, flags: flagNotNULL, decimals: decimalsNotFixed},
+		{name: "price", fieldType: fieldTypeNewDecimal, length: 12, decimals: 2, flags: flagNotNULL},
+		{name: "created", fieldType: fieldTypeDateTime},
+		{name: "year", fieldType: fieldTypeYear, flags: flagUnsigned | flagZeroFill},
+		{name: "duration", fieldType: fieldTypeTime},
+		{name: "name", fieldType: fieldTypeVarString, length: 80},
+		{name: "state", fieldType: fieldTypeString, flags: flagEnum},
+		{name: "body", fieldType: fieldTypeBLOB, flags: flagBinary},
+	})
+	expected := []ArrowField{
+		{Name: "id", Type: ArrowUint64},
+		{Name: "qty", Type: ArrowInt16, Nullable: true},
+		{Name: "ratio", Type: ArrowFloat32, Nullable: true},
+		{Name: "score", Type: ArrowFloat64},
+		{Name: "price", Type: ArrowDecimal128, Precision: 10, Scale: 2},
+		{Name: "created", Type: ArrowTimestamp, Nullable: true},
+		{Name: "year", Type: ArrowInt16, Nullable: true},
+		{Name: "duration", Type: ArrowUtf8, Nullable: true},
+		{Name: "name", Type: ArrowUtf8, Nullable: true},
+		{Name: "state", Type: ArrowUtf8, Nullable: true},
+		{Name: "body", Type: ArrowBinary, Nullable: true},
+	}
+	fields, err := ArrowFields(cols)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("expected fields\n%+v\ngot\n%+v\n", expected, fields)
+	}
+	failing := [][]mysqlField{
+		{{name: "n", fieldType: fieldTypeNULL}},
+		{{name: "big", fieldType: fieldTypeNewDecimal, length: 66, decimals: 0}},
+	}
+	for _, fields := range failing {
+		if _, err := ArrowFields(fieldColumns(fields)); err == nil {
+			t.Errorf("expected an error for %s\n", fields[0].name)
+		}
+	}
+}
+
+func TestColumnCountMismatch(t *testing.T) {
+	fields := []mysqlField{
+		{name: "id", fieldType: fieldTypeLong},
+		{name: "name", fieldType: fieldTypeVarString},
+	}
+	// the driver reports one column, but two are read from the layout
+	dRows := newFakeRows(false, fields).(*textRows)
+	dRows.rs.columnNames = []string{"id"}
+	rows := fakeQuery(t, dRows)
+	defer rows.Close()
+	var colErr *ColumnsError
+	if cols, err := Columns(rows); !errors.As(err, &colErr) || colErr.Stage != StageColumnCountMismatch || cols != nil {
+		t.Errorf("expected a column count mismatch, got %v, %v\n", cols, err)
+	}
+	if n, err := ReadColumnInfo(make([]ColumnInfo, 2), rows); !errors.As(err, &colErr) || n != 0 {
+		t.Errorf("expected ReadColumnInfo to fail, got %d, %v\n", n, err)
+	}
+	// ColumnsUnchecked does not compare the columns
+	if cols, err := ColumnsUnchecked(rows); err != nil || len(cols) != 2 {
+		t.Errorf("expected ColumnsUnchecked to return the 2 columns of the layout, got %v, %v\n", cols, err)
+	}
+	dRows.rs.columnNames = []string{"id", "name"}
+	if cols, err := Columns(rows); err != nil || len(cols) != 2 {
+		t.Errorf("expected 2 columns, got %v, %v\n", cols, err)
+	}
+}
+
+func TestColumnDetails(t *testing.T) {
+	fields := []mysqlField{
+		{tableName: "t", name: "id", fieldType: fieldTypeLong, flags: flagNotNULL | flagPriKey, length: 11},
+		{tableName: "t", name: "name", fieldType: fieldTypeVarString, length: 80, charSet: 45},
+	}
+	rows := fakeQuery(t, newFakeRows(false, fields))
+	defer rows.Close()
+	details, err := ColumnDetails(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cols, err := Columns(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := ColumnInfo{TableName: "t", Name: "id", Type: fieldTypeLong, Flags: uint16(flagNotNULL | flagPriKey), Length: 11}
+	if len(details) != 2 || details[0] != expected {
+		t.Fatalf("expected %+v first, got %+v\n", expected, details)
+	}
+	for i, detail := range details {
+		col := cols[i]
+		if detail.Name != col.Name() || detail.TableName != col.TableName() ||
+			detail.Column().MysqlType() != col.MysqlType() || detail.Column().IsNotNull() != col.IsNotNull() {
+			t.Errorf("column %d: %+v does not match %#v\n", i, detail, col)
+		}
+		if !reflect.DeepEqual(detail.Column(), col) {
+			t.Errorf("column %d: expected %#v, got %#v\n", i, col, detail.Column())
+		}
+	}
+	if _, err := ColumnDetails(nil); err == nil {
+		t.Errorf("expected an error for nil\n")
+	}
+}
+
+func TestTextAndBlob(t *testing.T) {
+	const utf8mb4GeneralCI = 45
+	tests := []struct {
+		field     mysqlField
+		mysqlType string
+		text      bool
+		goType    reflect.Type
+		sqlType   reflect.Type
+	}{
+		{mysqlField{fieldType: fieldTypeBLOB, charSet: utf8mb4GeneralCI, length: 262140},
+			"TEXT", true, typeString, typeNullString},
+		{mysqlField{fieldType: fieldTypeLongBLOB, charSet: utf8mb4GeneralCI},
+			"LONGTEXT", true, typeString, typeNullString},
+		{mysqlField{fieldType: fieldTypeBLOB, charSet: binaryCharSet, flags: flagBinary, length: 65535},
+			"BLOB", false, typeBytes, typeBytes},
+		// unknown collation
+		{mysqlField{fieldType: fieldTypeTinyBLOB},
+			"TINY BLOB", false, typeBytes, typeBytes},
+	}
+	for _, test := range tests {
+		f := test.field
+		if f.MysqlType() != test.mysqlType {
+			t.Errorf("expected type %s, got %s\n", test.mysqlType, f.MysqlType())
+		}
+		if f.IsText() != test.text || f.IsBlob() == test.text {
+			t.Errorf("%s: expected IsText %t, got IsText %t and IsBlob %t\n", test.mysqlType, test.text, f.IsText(), f.IsBlob())
+		}
+		if goType, err := f.ReflectGoType(); err != nil || goType != test.goType {
+			t.Errorf("%s: expected Go type %v, got %v, error %v\n", test.mysqlType, test.goType, goType, err)
+		}
+		if sqlType, err := f.ReflectSqlType(false); err != nil || sqlType != test.sqlType {
+			t.Errorf("%s: expected SQL type %v, got %v, error %v\n", test.mysqlType, test.sqlType, sqlType, err)
+		}
+		if length, ok := f.MaxLength(); !ok || length != int64(f.length) {
+			t.Errorf("%s: expected a maximum length of %d, got %d\n", test.mysqlType, f.length, length)
+		}
+	}
+	text := tests[0].field
+	if decl, err := text.MysqlDeclarationWithCharset(); err != nil ||
+		decl != "TEXT CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci" {
+		t.Errorf("unexpected TEXT declaration '%s', error %v\n", decl, err)
+	}
+}
+
+func TestColumnsWithSchemaWithoutTables(t *testing.T) {
+	fields := []mysqlField{
+		{name: "1", fieldType: fieldTypeLongLong, flags: flagNotNULL},
+		{name: "NOW()", fieldType: fieldTypeDateTime},
+	}
+	rows := fakeQuery(t, newFakeRows(false, fields))
+	defer rows.Close()
+	if _, err := ColumnsWithSchema(nil, rows); err != errNilConn {
+		t.Errorf("expected errNilConn, got %v\n", err)
+	}
+	// expressions don't query information_schema, so the fake conn is not used
+	fake := sql.OpenDB(&fakeDB{})
+	defer fake.Close()
+	conn := schemaConn(t, fake)
+	defer conn.Close()
+	cols, err := ColumnsWithSchema(conn, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, col := range cols {
+		schemaCol, ok := col.(SchemaColumn)
+		if !ok {
+			t.Fatalf("column %d: expected a SchemaColumn, got %#v\n", i, col)
+		}
+		if schemaCol.FromSchema() || schemaCol.IsNotNull() != fields[i].IsNotNull() {
+			t.Errorf("column %d: expected the flags of the result, got %#v\n", i, col)
+		}
+		if _, ok := schemaCol.DefaultValue(); ok {
+			t.Errorf("column %d: expected no default value\n", i)
+		}
+		if schemaCol.OnUpdateCurrentTimestamp() || schemaCol.NoDefaultValue() {
+			t.Errorf("column %d: expected no attributes from information_schema\n", i)
+		}
+	}
+}
+
+func TestColumnsWithSchema(t *testing.T) {
+	db := openTestSchema(t)
+	defer db.Close()
+	for _, stmt := range []string{
+		"DROP TABLE IF EXISTS attrs",
+		"CREATE TABLE attrs (id INT NOT NULL AUTO_INCREMENT PRIMARY KEY," +
+			" code VARCHAR(10) NOT NULL UNIQUE, note VARCHAR(20) DEFAULT 'none', KEY (note))",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer db.Exec("DROP TABLE attrs")
+	rows, err := db.Query("SELECT id, code, note, 1 AS one FROM attrs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	flagCols, err := Columns(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a second connection for information_schema
+	schemaDB := openTestSchema(t)
+	defer schemaDB.Close()
+	conn := schemaConn(t, schemaDB)
+	defer conn.Close()
+	cols, err := ColumnsWithSchema(conn, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		fromSchema bool
+		notNull    bool
+		key        KeyKind
+		autoInc    bool
+		def        string
+		hasDefault bool
+	}{
+		{true, true, KeyPrimary, true, "", false},
+		{true, true, KeyUnique, false, "", false},
+		{true, false, KeyMultiple, false, "none", true},
+		{false, true, KeyNone, false, "", false},
+	}
+	for i, expected := range tests {
+		col := cols[i].(SchemaColumn)
+		def, hasDefault := col.DefaultValue()
+		if col.FromSchema() != expected.fromSchema || col.IsNotNull() != expected.notNull ||
+			col.KeyKind() != expected.key || col.IsAutoIncrement() != expected.autoInc ||
+			def != expected.def || hasDefault != expected.hasDefault {
+			t.Errorf("column %d: expected %+v, got %#v with default %q, %t\n", i, expected, col, def, hasDefault)
+		}
+		if flagCols[i].Name() != col.Name() || flagCols[i].MysqlType() != col.MysqlType() {
+			t.Errorf("column %d: expected %#v to match the flag-derived %#v\n", i, col, flagCols[i])
+		}
+	}
+}
+
+func TestColumnsWithSchemaTimestamps(t *testing.T) {
+	db := openTestSchema(t)
+	defer db.Close()
+	for _, stmt := range []string{
+		"DROP TABLE IF EXISTS stamps",
+		"CREATE TABLE stamps (id INT NOT NULL AUTO_INCREMENT PRIMARY KEY," +
+			" updated TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP," +
+			" name VARCHAR(10) NOT NULL, note VARCHAR(10))",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer db.Exec("DROP TABLE stamps")
+	rows, err := db.Query("SELECT id, updated, name, note FROM stamps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	schemaDB := openTestSchema(t)
+	defer schemaDB.Close()
+	conn := schemaConn(t, schemaDB)
+	defer conn.Close()
+	cols, err := ColumnsWithSchema(conn, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		onUpdate  bool
+		noDefault bool
+	}{
+		{false, false},
+		{true, false},
+		{false, true},
+		{false, false},
+	}
+	for i, expected := range tests {
+		col := cols[i].(SchemaColumn)
+		if col.OnUpdateCurrentTimestamp() != expected.onUpdate || col.NoDefaultValue() != expected.noDefault {
+			t.Errorf("column %s: expected %+v, got on update %t and no default %t\n",
+				col.Name(), expected, col.OnUpdateCurrentTimestamp(), col.NoDefaultValue())
+		}
+	}
+}
+
+func TestColumnsFromInspected(t *testing.T) {
+	fields := []mysqlField{
+		{name: "id", fieldType: fieldTypeLong, flags: flagNotNULL},
+		{name: "name", fieldType: fieldTypeVarString},
+	}
+	rows := fakeQuery(t, newFakeRows(true, fields))
+	defer rows.Close()
+	inspected, err := sqlinternals.Inspect(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cols, err := ColumnsFromInspected(inspected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cols) != len(fields) || cols[0].Name() != "id" || cols[1].Name() != "name" {
+		t.Errorf("unexpected columns %#v\n", cols)
+	}
+	var colErr *ColumnsError
+	for arg, stage := range map[interface{}]InspectStage{
+		"not rows":       StageNotRows,
+		&uncountedRows{}: StageNotMysql,
+	} {
+		if _, err := ColumnsFromInspected(arg); !errors.As(err, &colErr) || colErr.Stage != stage {
+			t.Errorf("expected stage %v for %T, got %v\n", stage, arg, err)
+		}
+	}
+	if _, err := ColumnsFromInspected(nil); !errors.As(err, &colErr) || colErr.Stage != StageNil {
+		t.Errorf("expected stage %v for nil, got %v\n", StageNil, err)
+	}
+}
+
+func TestIsBinaryInterpolateParams(t *testing.T) {
+	interpolateDSN := dsn + "?interpolateParams=true"
+	if strings.Contains(dsn, "?") {
+		interpolateDSN = dsn + "&interpolateParams=true"
+	}
+	db, err := sql.Open("mysql", interpolateDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// arguments are interpolated, the text protocol is used
+	rows, err := db.Query("SELECT ? + 1", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	if binary, err := IsBinary(rows); err != nil || binary {
+		t.Errorf("expected the text protocol for interpolated arguments, got %t, '%v'\n", binary, err)
+	}
+	stmt, err := db.Prepare("SELECT ? + 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	stmtRows, err := stmt.Query(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmtRows.Close()
+	if binary, err := IsBinary(stmtRows); err != nil || !binary {
+		t.Errorf("expected the binary protocol for a prepared statement, got %t, '%v'\n", binary, err)
+	}
+}
+
+func TestPlaceholdersAndColumnNameList(t *testing.T) {
+	cols := fieldColumns([]mysqlField{
+		{name: "id", fieldType: fieldTypeLong},
+		{name: "name", fieldType: fieldTypeVarString},
+		{name: "price", fieldType: fieldTypeNewDecimal},
+		{name: "created at", fieldType: fieldTypeDateTime},
+		{name: "odd`name", fieldType: fieldTypeBLOB},
+	})
+	if placeholders := Placeholders(cols); placeholders != "(?, ?, ?, ?, ?)" {
+		t.Errorf("unexpected placeholders %s\n", placeholders)
+	}
+	if names := ColumnNameList(cols); names != "`id`, `name`, `price`, `created at`, `odd``name`" {
+		t.Errorf("unexpected name list %s\n", names)
+	}
+	if placeholders, names := Placeholders(nil), ColumnNameList(nil); placeholders != "()" || names != "" {
+		t.Errorf("unexpected results %q and %q without columns\n", placeholders, names)
+	}
+}
+
+func TestSetValues(t *testing.T) {
+	// SET('a','b','c')
+	field := mysqlField{fieldType: fieldTypeSet, flags: flagSet}
+	if goType, err := field.ReflectGoType(); err != nil || goType != typeStrings {
+		t.Errorf("expected []string, got %v, error %v\n", goType, err)
+	}
+	if sqlType, err := field.ReflectSqlType(true); err != nil || sqlType != typeStrings {
+		t.Errorf("expected nullable []string, got %v, error %v\n", sqlType, err)
+	}
+	tests := []struct {
+		src      interface{}
+		expected interface{}
+	}{
+		{[]byte("a,c"), []string{"a", "c"}},
+		{"b", []string{"b"}},
+		{[]byte{}, []string{}},
+		{nil, nil},
+	}
+	for _, test := range tests {
+		value, err := field.ConvertValue(test.src)
+		if err != nil || !reflect.DeepEqual(value, test.expected) {
+			t.Errorf("expected %#v for %#v, got %#v, error %v\n", test.expected, test.src, value, err)
+		}
+	}
+	if _, err := field.ConvertValue(42); err == nil {
+		t.Errorf("expected an error for an int\n")
+	}
+	// other types are not converted
+	if value, err := (mysqlField{fieldType: fieldTypeVarString}).ConvertValue([]byte("a,c")); err != nil ||
+		!reflect.DeepEqual(value, []byte("a,c")) {
+		t.Errorf("expected the unchanged value, got %#v, error %v\n", value, err)
+	}
+}
+
+func TestEnumGoType(t *testing.T) {
+	// ENUM('active','inactive')
+	col := mysqlField{name: "status", fieldType: fieldTypeEnum, flags: flagEnum}
+	src, err := EnumGoType(col, "Status", []string{"active", "inactive"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `// Status is a member of the ENUM column status.
+type Status string
+
+const (
+	StatusActive   Status = "active"
+	StatusInactive Status = "inactive"
+)
+`
+	if src != expected {
+		t.Errorf("expected\n%s\ngot\n%s\n", expected, src)
+	}
+	failing := []struct {
+		col      mysqlField
+		typeName string
+		members  []string
+	}{
+		{mysqlField{name: "n", fieldType: fieldTypeLong}, "Status", []string{"a"}},
+		{col, "not a name", []string{"a"}},
+		{col, "Status", []string{"in-progress", "in progress"}},
+	}
+	for _, test := range failing {
+		if _, err := EnumGoType(test.col, test.typeName, test.members); err == nil {
+			t.Errorf("expected an error for %s %q with %q\n", test.col.MysqlType(), test.typeName, test.members)
+		}
+	}
+}
+
+func TestColumnsFromStmt(t *testing.T) {
+	fields := []mysqlField{
+		{tableName: "t", name: "id", fieldType: fieldTypeLong, flags: flagNotNULL | flagPriKey},
+		{tableName: "t", name: "name", fieldType: fieldTypeVarString, length: 80},
+	}
+	cols, err := ColumnsFromStmt(&mysqlStmt{paramCount: 1, columns: fields})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cols) != len(fields) || !reflect.DeepEqual(cols[0], Column(fields[0])) || !reflect.DeepEqual(cols[1], Column(fields[1])) {
+		t.Errorf("unexpected columns %#v\n", cols)
+	}
+	if _, err := ColumnsFromStmt(&mysqlStmt{}); err != ErrNoResultSet {
+		t.Errorf("expected ErrNoResultSet without columns, got %v\n", err)
+	}
+	{
+		// driver versions discarding the column definitions
+		type mysqlStmt struct {
+			driver.Stmt
+			id uint32
+		}
+		if _, err := ColumnsFromStmt(&mysqlStmt{Stmt: &fakeDB{}}); err != ErrNoColumnMetadata {
+			t.Errorf("expected ErrNoColumnMetadata for a mysqlStmt without columns, got %v\n", err)
+		}
+	}
+	var colErr *ColumnsError
+	for stmt, stage := range map[driver.Stmt]InspectStage{
+		nil:               StageNil,
+		&fakeDB{}:         StageNotMysql,
+		(*mysqlStmt)(nil): StageNotMysql,
+	} {
+		if _, err := ColumnsFromStmt(stmt); !errors.As(err, &colErr) || colErr.Stage != stage {
+			t.Errorf("expected stage %v for %T, got %v\n", stage, stmt, err)
+		}
+	}
+}
+
+func TestRegisterLayoutRemoval(t *testing.T) {
+	type removedRows struct {
+		uncountedRows
+	}
+	RegisterLayout("removedRows", func(reflect.Type) (Layout, error) {
+		return Layout{Columns: func(driver.Rows) []Column { return nil }}, nil
+	})
+	RegisterLayout("removedRows", nil)
+	rows := fakeQuery(t, &removedRows{uncountedRows{columns: []string{"a"}}})
+	defer rows.Close()
+	var colErr *ColumnsError
+	if _, err := Columns(rows); !errors.As(err, &colErr) || colErr.Stage != StageNotMysql {
+		t.Errorf("expected stage %v for a removed layout, got %v\n", StageNotMysql, err)
+	}
+}
+
+func TestColumnsRecoversPanic(t *testing.T) {
+	// embedded for the methods, the name is unique to this test
+	type panicRows struct {
+		uncountedRows
+	}
+	// a corrupt layout reading past the end of the columns
+	RegisterLayout("panicRows", func(reflect.Type) (Layout, error) {
+		return Layout{
+			Columns: func(rows driver.Rows) []Column {
+				var fields []mysqlField
+				return []Column{fields[len(rows.Columns())]}
+			},
+		}, nil
+	})
+	defer RegisterLayout("panicRows", nil)
+	rows := fakeQuery(t, &panicRows{uncountedRows{columns: []string{"a"}}})
+	defer rows.Close()
+	cols, err := Columns(rows)
+	var colErr *ColumnsError
+	if !errors.As(err, &colErr) || colErr.Stage != StagePanic || cols != nil {
+		t.Fatalf("expected a recovered panic, got %v, %v\n", cols, err)
+	}
+	var runtimeErr runtime.Error
+	if colErr.Recovered == nil || !errors.As(err, &runtimeErr) {
+		t.Errorf("expected the recovered runtime error to be wrapped, got %#v\n", colErr.Recovered)
+	}
+	if n, err := ReadColumnInfo(make([]ColumnInfo, 1), rows); !errors.As(err, &colErr) || colErr.Stage != StagePanic || n != 0 {
+		t.Errorf("expected a recovered panic from ReadColumnInfo, got %d, %v\n", n, err)
+	}
+}
+
+func TestCompareProtocolColumns(t *testing.T) {
+	// SELECT 1, 'a'
+	text := fieldColumns([]mysqlField{
+		{name: "1", fieldType: fieldTypeLongLong, flags: flagNotNULL | flagBinary},
+		{name: "a", fieldType: fieldTypeVarString, flags: flagNotNULL},
+	})
+	binary := fieldColumns([]mysqlField{
+		{name: "1", fieldType: fieldTypeLongLong, flags: flagNotNULL | flagBinary},
+		{name: "a", fieldType: fieldTypeVarString, flags: flagNotNULL},
+	})
+	diffs := compareProtocolColumns(text, binary)
+	if len(diffs) != 1 || diffs[0].Index != 0 || !reflect.DeepEqual(diffs[0].Differences, []string{"DriverValueKind"}) {
+		t.Fatalf("expected the int-vs-string difference of the first column, got %+v\n", diffs)
+	}
+	binary[1] = mysqlField{name: "a", fieldType: fieldTypeString, flags: flagNotNULL | flagBinary}
+	diffs = compareProtocolColumns(text, binary)
+	if len(diffs) != 2 || !reflect.DeepEqual(diffs[1].Differences, []string{"MysqlType", "Flags"}) {
+		t.Errorf("expected type and flag differences in the second column, got %+v\n", diffs)
+	}
+}
+
+func TestCompareProtocolsRejectsStatements(t *testing.T) {
+	for _, query := range []string{"SELECT 1", " (select 1)", "WITH a AS (SELECT 1) SELECT * FROM a", "SHOW TABLES", "TABLE t", "VALUES ROW(1)"} {
+		if !isQuery(query) {
+			t.Errorf("expected %q to be accepted\n", query)
+		}
+	}
+	db := sql.OpenDB(&fakeDB{})
+	defer db.Close()
+	for _, query := range []string{"", "DELETE FROM t", "INSERT INTO t SELECT 1", "/* SELECT */ DELETE FROM t", "SELECTED"} {
+		if isQuery(query) {
+			t.Errorf("expected %q to be rejected\n", query)
+		}
+		if _, err := CompareProtocols(db, query); err == nil {
+			t.Errorf("expected an error for %q\n", query)
+		}
+	}
+}
+
+func TestCompareProtocols(t *testing.T) {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	diffs, err := CompareProtocols(db, "SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 1 || diffs[0].Text.DriverValueKind(false, false) != reflect.Slice ||
+		diffs[0].Binary.DriverValueKind(true, false) != reflect.Int64 {
+		t.Errorf("expected []byte for the text and int64 for the binary protocol, got %+v\n", diffs)
+	}
+	if _, err := CompareProtocols(db, "SELECT ?", 1); err == nil {
+		t.Errorf("expected an error for arguments without interpolateParams\n")
+	}
+}
+
+func TestAutoIncrementInBothProtocols(t *testing.T) {
+	db := openTestSchema(t)
+	defer db.Close()
+	for _, stmt := range []string{
+		"DROP TABLE IF EXISTS autoinc",
+		"CREATE TABLE autoinc (id INT NOT NULL AUTO_INCREMENT PRIMARY KEY, n INT)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer db.Exec("DROP TABLE autoinc")
+	const query = "SELECT id, n, id + 1 AS next FROM autoinc"
+	diffs, err := CompareProtocols(db, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, diff := range diffs {
+		for _, difference := range diff.Differences {
+			if difference == "Flags" {
+				t.Errorf("column %d: flags differ between the protocols\n", diff.Index)
+			}
+		}
+	}
+	// the current behavior: only the column of the table is marked
+	for _, binary := range []bool{false, true} {
+		var rows *sql.Rows
+		if binary {
+			rows, err = db.Query(query+" WHERE ? = ?", 1, 1)
+		} else {
+			rows, err = db.Query(query)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		cols, isBinary, err := ColumnsAndProtocol(rows)
+		rows.Close()
+		if err != nil || isBinary != binary {
+			t.Fatalf("expected binary protocol %t, got %t, '%v'\n", binary, isBinary, err)
+		}
+		for i, expected := range []bool{true, false, false} {
+			if cols[i].IsAutoIncrement() != expected {
+				t.Errorf("binary protocol %t, column %s: expected AUTO_INCREMENT %t\n", binary, cols[i].Name(), expected)
+			}
+		}
+	}
+}
+
+func TestCharLength(t *testing.T) {
+	const (
+		latin1GermanCI   = 5
+		latin1SwedishCI  = 8
+		latin1SpanishCI  = 94
+		utf8Bin          = 83
+		utf8mb4GeneralCI = 45
+	)
+	tests := []struct {
+		field  mysqlField
+		length int64
+		ok     bool
+	}{
+		// VARCHAR(10)
+		{mysqlField{fieldType: fieldTypeVarString, length: 40, charSet: utf8mb4GeneralCI}, 10, true},
+		{mysqlField{fieldType: fieldTypeVarString, length: 10, charSet: latin1SpanishCI}, 10, true},
+		// the default collation of latin1
+		{mysqlField{fieldType: fieldTypeVarString, length: 10, charSet: latin1SwedishCI}, 10, true},
+		{mysqlField{fieldType: fieldTypeString, length: 30, charSet: utf8Bin, flags: flagBinary}, 10, true},
+		// TEXT
+		{mysqlField{fieldType: fieldTypeBLOB, length: 262140, charSet: utf8mb4GeneralCI}, 65535, true},
+		// unknown collation
+		{mysqlField{fieldType: fieldTypeVarString, length: 40}, 0, false},
+		// may be aliased by utf8mb4 collations above 255
+		{mysqlField{fieldType: fieldTypeVarString, length: 10, charSet: latin1GermanCI}, 0, false},
+		{mysqlField{fieldType: fieldTypeVarString, length: 10, charSet: binaryCharSet, flags: flagBinary}, 0, false},
+		{mysqlField{fieldType: fieldTypeLong, length: 11, charSet: binaryCharSet}, 0, false},
+	}
+	for _, test := range tests {
+		if length, ok := test.field.CharLength(); length != test.length || ok != test.ok {
+			t.Errorf("expected (%d, %t) for %s with collation %d, got (%d, %t)\n",
+				test.length, test.ok, test.field.MysqlType(), test.field.charSet, length, ok)
+		}
+	}
+}
+
+func TestScanRowInto(t *testing.T) {
+	// SELECT u.id, u.name, o.id, o.total, o.status, o.note FROM users u JOIN orders o ...
+	fields := []mysqlField{
+		{name: "id", tableName: "u", fieldType: fieldTypeLong, flags: flagNotNULL | flagPriKey},
+		{name: "name", tableName: "u", fieldType: fieldTypeVarString, charSet: 45},
+		{name: "id", tableName: "o", fieldType: fieldTypeLongLong, flags: flagNotNULL | flagUnsigned | flagPriKey},
+		{name: "total", tableName: "o", fieldType: fieldTypeNewDecimal, flags: flagNotNULL, length: 12, decimals: 2},
+		{name: "status", tableName: "o", fieldType: fieldTypeString, flags: flagNotNULL | flagEnum, charSet: 45},
+		{name: "note", tableName: "o", fieldType: fieldTypeVarString, charSet: 45},
+	}
+	type userOrder struct {
+		UserID  int32   `db:"u.id"`
+		Name    *string `db:"name"`
+		OrderID uint64  `db:"o.id"`
+		Total   float64
+		Status  string
+		Comment *string
+		ignored int
+	}
+	rows := fakeQuery(t, newFakeRows(false, fields,
+		[]driver.Value{[]byte("7"), nil, []byte("18446744073709551615"), []byte("12.50"), []byte("paid"), []byte("-")},
+		[]driver.Value{[]byte("8"), []byte("Bob"), []byte("1"), []byte("0.99"), []byte("open"), nil},
+	))
+	defer rows.Close()
+	expected := []userOrder{
+		{UserID: 7, OrderID: math.MaxUint64, Total: 12.5, Status: "paid"},
+		{UserID: 8, Name: new(string), OrderID: 1, Total: 0.99, Status: "open"},
+	}
+	*expected[1].Name = "Bob"
+	for _, exp := range expected {
+		if !rows.Next() {
+			t.Fatalf("expected more rows: %v\n", rows.Err())
+		}
+		var got userOrder
+		if err := ScanRowInto(rows, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.UserID != exp.UserID || got.OrderID != exp.OrderID || got.Total != exp.Total ||
+			got.Status != exp.Status || got.Comment != nil {
+			t.Errorf("expected %+v, got %+v\n", exp, got)
+		}
+		if (got.Name == nil) != (exp.Name == nil) || got.Name != nil && *got.Name != *exp.Name {
+			t.Errorf("expected name %v, got %v\n", exp.Name, got.Name)
+		}
+	}
+}
+
+func TestScanRowIntoErrors(t *testing.T) {
+	fields := []mysqlField{
+		{name: "id", fieldType: fieldTypeLong, flags: flagNotNULL},
+		{name: "name", fieldType: fieldTypeVarString, charSet: 45},
+	}
+	tests := []struct {
+		dst      interface{}
+		expected string
+	}{
+		{struct{ Id int }{}, "ScanRowInto: dst must be a non-nil pointer to a struct"},
+		{&struct {
+			Id    int
+			Email string
+		}{}, "ScanRowInto: no column for required field Email"},
+		{&struct {
+			Id   int
+			Name string
+		}{}, `ScanRowInto: field Name for column "name": NULL can't be stored in string`},
+		{&struct {
+			Id   int8
+			Name *string
+		}{}, `ScanRowInto: field Id for column "id": 1000 overflows int8`},
+	}
+	for _, test := range tests {
+		rows := fakeQuery(t, newFakeRows(false, fields, []driver.Value{[]byte("1000"), nil}))
+		if !rows.Next() {
+			t.Fatalf("expected a row: %v\n", rows.Err())
+		}
+		err := ScanRowInto(rows, test.dst)
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("expected error %q, got %v\n", test.expected, err)
+		}
+		rows.Close()
+	}
+}
+
+func TestServerVersion(t *testing.T) {
+	if version, ok := ServerVersion(fakeQuery(t, newFakeRows(false, nil))); ok || version != "" {
+		t.Errorf("expected no version for fake rows, got %q\n", version)
+	}
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	rows, err := db.Query("SELECT VERSION()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	// the driver doesn't keep the version of the handshake
+	if version, ok := ServerVersion(rows); ok || version != "" {
+		t.Errorf("expected no version from the rows, got %q\n", version)
+	}
+	var version string
+	if !rows.Next() {
+		t.Fatalf("expected a row: %v\n", rows.Err())
+	}
+	if err := rows.Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if len(version) == 0 || version[0] < '1' || version[0] > '9' || !strings.Contains(version, ".") {
+		t.Errorf("expected a plausible server version, got %q\n", version)
+	}
+}
+
+func TestColumnsAfterCancel(t *testing.T) {
+	fields := []mysqlField{
+		{name: "id", fieldType: fieldTypeLong, flags: flagNotNULL},
+		{name: "name", fieldType: fieldTypeVarString, charSet: 45},
+	}
+	dRows := newFakeRows(false, fields, []driver.Value{[]byte("1"), []byte("a")})
+	dRows.(*textRows).repeat = true
+	db := sql.OpenDB(&fakeDB{rows: dRows})
+	defer db.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rows, err := db.QueryContext(ctx, "fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		t.Fatalf("expected a row: %v\n", rows.Err())
+	}
+	cancel()
+	for rows.Next() {
+		// the endless rows are closed by database/sql when it notices the cancellation
+	}
+	if err := rows.Err(); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v\n", err)
+	}
+	cols, err := Columns(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cols) != len(fields) {
+		t.Fatalf("expected %d columns, got %d\n", len(fields), len(cols))
+	}
+	for i, col := range cols {
+		if col.Name() != fields[i].name || col.MysqlType() != fields[i].MysqlType() {
+			t.Errorf("expected column %s %s, got %s %s\n",
+				fields[i].name, fields[i].MysqlType(), col.Name(), col.MysqlType())
+		}
+	}
+}
+
+func TestSetTypeNameOverride(t *testing.T) {
+	defer SetTypeNameOverride(fieldTypeTiny, nil)
+	SetTypeNameOverride(fieldTypeTiny, func(info ColumnInfo, name string) string {
+		if info.Length == 1 && info.Flags&uint16(flagUnsigned) == 0 {
+			return "BOOLEAN"
+		}
+		return name
+	})
+	tests := []struct {
+		field       mysqlField
+		name        string
+		declaration string
+	}{
+		{mysqlField{fieldType: fieldTypeTiny, length: 1, flags: flagNotNULL}, "BOOLEAN", "BOOLEAN NOT NULL"},
+		{mysqlField{fieldType: fieldTypeTiny, length: 4}, "TINYINT", "TINYINT"},
+		{mysqlField{fieldType: fieldTypeTiny, length: 3, flags: flagUnsigned}, "TINYINT", "TINYINT UNSIGNED"},
+		{mysqlField{fieldType: fieldTypeShort, length: 1}, "SMALLINT", "SMALLINT"},
+	}
+	for _, test := range tests {
+		if name := test.field.MysqlType(); name != test.name {
+			t.Errorf("expected type name %s, got %s\n", test.name, name)
+		}
+		if decl, err := test.field.MysqlDeclaration(); err != nil || decl != test.declaration {
+			t.Errorf("expected declaration %q, got %q (%v)\n", test.declaration, decl, err)
+		}
+		if !test.field.IsInteger() {
+			t.Errorf("override of the name must not change the category of %s\n", test.name)
+		}
+	}
+	SetTypeNameOverride(fieldTypeTiny, nil)
+	if name := tests[0].field.MysqlType(); name != "TINYINT" {
+		t.Errorf("expected TINYINT after removing the override, got %s\n", name)
+	}
+}
+
+func TestDriverScanType(t *testing.T) {
+	tests := []struct {
+		field    mysqlField
+		driver   reflect.Type
+		reflectT reflect.Type
+	}{
+		// identical for NOT NULL numbers
+		{mysqlField{fieldType: fieldTypeLong, flags: flagNotNULL}, typeInt32, typeInt32},
+		{mysqlField{fieldType: fieldTypeDouble, flags: flagNotNULL}, typeFloat64, typeFloat64},
+		// the driver uses nullable types, ReflectGoType ignores NULL
+		{mysqlField{fieldType: fieldTypeLongLong}, typeNullInt64, typeInt64},
+		{mysqlField{fieldType: fieldTypeDateTime, flags: flagNotNULL}, typeNullTime, typeTime},
+		// the driver keeps the raw bytes
+		{mysqlField{fieldType: fieldTypeNewDecimal, flags: flagNotNULL, length: 12, decimals: 2}, typeRawBytes, typeBigint},
+		{mysqlField{fieldType: fieldTypeVarString, flags: flagNotNULL, charSet: 45}, typeRawBytes, typeString},
+	}
+	fields := make([]mysqlField, len(tests))
+	for i, test := range tests {
+		fields[i] = test.field
+		fields[i].name = "c" + strconv.Itoa(i)
+	}
+	rows := fakeQuery(t, newFakeRows(false, fields))
+	defer rows.Close()
+	for i, test := range tests {
+		driverType, ok := DriverScanType(rows, i)
+		if !ok || driverType != test.driver {
+			t.Errorf("expected driver scan type %v for %s, got %v\n", test.driver, test.field.MysqlType(), driverType)
+		}
+		if goType, err := test.field.ReflectGoType(); err != nil || goType != test.reflectT {
+			t.Errorf("expected ReflectGoType %v for %s, got %v (%v)\n", test.reflectT, test.field.MysqlType(), goType, err)
+		}
+	}
+	if _, ok := DriverScanType(rows, len(tests)); ok {
+		t.Errorf("expected no scan type for an index out of range\n")
+	}
+	// ScanType mirrors the driver's scan types
+	for _, fieldType := range allFieldTypes {
+		for _, flags := range []fieldFlag{0, flagNotNULL, flagNotNULL | flagUnsigned} {
+			f := mysqlField{fieldType: fieldType, flags: flags}
+			if scanType, driverType := f.ScanType(), driverScanType(f); scanType != driverType {
+				t.Errorf("type %d with flags %d: expected the driver's scan type %v, got %v\n",
+					fieldType, flags, driverType, scanType)
+			}
+		}
+	}
+	if _, ok := DriverScanType(nil, 0); ok {
+		t.Errorf("expected no scan type for nil\n")
+	}
+	untyped := fakeQuery(t, &uncountedRows{columns: []string{"a"}})
+	defer untyped.Close()
+	if _, ok := DriverScanType(untyped, 0); ok {
+		t.Errorf("expected no scan type for rows without driver.RowsColumnTypeScanType\n")
+	}
+}
+
+func TestAssignableToKind(t *testing.T) {
+	tests := []struct {
+		field      mysqlField
+		kind       reflect.Kind
+		assignable bool
+	}{
+		{mysqlField{fieldType: fieldTypeLong}, reflect.Int64, true},
+		{mysqlField{fieldType: fieldTypeLong}, reflect.Int32, true},
+		{mysqlField{fieldType: fieldTypeLong}, reflect.Int16, false},
+		{mysqlField{fieldType: fieldTypeLong}, reflect.Float64, true},
+		{mysqlField{fieldType: fieldTypeLong}, reflect.Bool, false},
+		{mysqlField{fieldType: fieldTypeLong}, reflect.String, true},
+		{mysqlField{fieldType: fieldTypeLong, flags: flagUnsigned}, reflect.Uint32, true},
+		{mysqlField{fieldType: fieldTypeLong}, reflect.Uint32, false},
+		{mysqlField{fieldType: fieldTypeVarString, charSet: 45}, reflect.Int, false},
+		{mysqlField{fieldType: fieldTypeVarString, charSet: 45}, reflect.String, true},
+		{mysqlField{fieldType: fieldTypeVarString, charSet: 45}, reflect.Slice, true},
+		{mysqlField{fieldType: fieldTypeDateTime}, reflect.Struct, true},
+		{mysqlField{fieldType: fieldTypeNewDecimal, length: 12, decimals: 2}, reflect.Float64, false},
+		{mysqlField{fieldType: fieldTypeNewDecimal, length: 12, decimals: 2}, reflect.Interface, true},
+		{mysqlField{fieldType: fieldTypeLong}, reflect.Ptr, false},
+	}
+	for _, test := range tests {
+		if assignable := test.field.AssignableToKind(test.kind); assignable != test.assignable {
+			t.Errorf("expected AssignableToKind(%v) of %s to be %t\n", test.kind, test.field.MysqlType(), test.assignable)
+		}
+	}
+}
+
+func TestColumnNameMismatch(t *testing.T) {
+	fields := []mysqlField{
+		{tableName: "t", name: "id", fieldType: fieldTypeLong},
+		{tableName: "t", name: "name", fieldType: fieldTypeVarString},
+	}
+	// the driver reports the columns in a different order than its fields
+	dRows := newFakeRows(false, fields).(*textRows)
+	dRows.rs.columnNames = []string{"name", "id"}
+	rows := fakeQuery(t, dRows)
+	defer rows.Close()
+	var colErr *ColumnsError
+	if cols, err := Columns(rows); !errors.As(err, &colErr) || colErr.Stage != StageColumnNameMismatch || cols != nil {
+		t.Errorf("expected a column name mismatch, got %v, %v\n", cols, err)
+	}
+	if n, err := ReadColumnInfo(make([]ColumnInfo, 2), rows); !errors.As(err, &colErr) || colErr.Stage != StageColumnNameMismatch || n != 0 {
+		t.Errorf("expected ReadColumnInfo to fail, got %d, %v\n", n, err)
+	}
+	// columnsWithAlias=true prefixes the names with the table
+	dRows.rs.columnNames = []string{"t.id", "t.name"}
+	if cols, err := Columns(rows); err != nil || len(cols) != 2 {
+		t.Errorf("expected 2 columns for names with tables, got %v, %v\n", cols, err)
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	original := Snapshot()
+	defer Restore(original)
+	tiny := mysqlField{name: "flag", fieldType: fieldTypeTiny, length: 1}
+	SetTypeNameOverride(fieldTypeTiny, func(info ColumnInfo, name string) string { return "BOOLEAN" })
+	FieldNamer = strings.ToUpper
+	UnboundedColumnBytes = 16
+	mutated := Snapshot()
+	SetTypeNameOverride(fieldTypeTiny, func(info ColumnInfo, name string) string { return "BOOL" })
+	RegisterFieldType(0xe0, "MEDIUMINT", CategoryInteger)
+	FieldNamer = strings.ToLower
+	if name := tiny.MysqlType(); name != "BOOL" {
+		t.Fatalf("expected the second override, got %s\n", name)
+	}
+	Restore(mutated)
+	if name := tiny.MysqlType(); name != "BOOLEAN" {
+		t.Errorf("expected the snapshot's override, got %s\n", name)
+	}
+	if name := FieldNamer("flag"); name != "FLAG" {
+		t.Errorf("expected the snapshot's FieldNamer, got %s\n", name)
+	}
+	if name := (mysqlField{fieldType: 0xe0}).MysqlType(); name != "" {
+		t.Errorf("expected the registration after the snapshot to be removed, got %s\n", name)
+	}
+	Restore(original)
+	if name := tiny.MysqlType(); name != "TINYINT" {
+		t.Errorf("expected the original type name, got %s\n", name)
+	}
+	if name := FieldNamer("flag"); name != "Flag" {
+		t.Errorf("expected the original FieldNamer, got %s\n", name)
+	}
+	if UnboundedColumnBytes != 4096 {
+		t.Errorf("expected the original UnboundedColumnBytes, got %d\n", UnboundedColumnBytes)
+	}
+	UnboundedColumnBytes = 16
+	Restore(Config{})
+	if UnboundedColumnBytes != 4096 || FieldNamer("flag") != "Flag" {
+		t.Errorf("expected the zero Config to restore the defaults\n")
+	}
+	// a snapshot without FieldNamer is not the zero Config
+	FieldNamer = nil
+	UnboundedColumnBytes = 16
+	withoutNamer := Snapshot()
+	Restore(Config{})
+	Restore(withoutNamer)
+	if UnboundedColumnBytes != 16 || FieldNamer != nil {
+		t.Errorf("expected the snapshot without FieldNamer to be restored, got %d bytes\n", UnboundedColumnBytes)
+	}
+	Restore(Config{})
+}
+
+func TestReadColumnar(t *testing.T) {
+	fields := []mysqlField{
+		{name: "id", fieldType: fieldTypeLong, flags: flagNotNULL},
+		{name: "name", fieldType: fieldTypeVarString, charSet: 45},
+		{name: "score", fieldType: fieldTypeDouble, decimals: decimalsNotFixed},
+	}
+	rows := fakeQuery(t, newFakeRows(false, fields,
+		[]driver.Value{[]byte("1"), []byte("ann"), []byte("1.5")},
+		[]driver.Value{[]byte("2"), nil, []byte("2")},
+		[]driver.Value{[]byte("3"), []byte("cy"), nil},
+		[]driver.Value{[]byte("4"), []byte("dee"), []byte("-0.25")},
+	))
+	defer rows.Close()
+	names, cols, err := ReadColumnar(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"id", "name", "score"}) {
+		t.Errorf("unexpected names %v\n", names)
+	}
+	expected := [][]interface{}{
+		{int32(1), int32(2), int32(3), int32(4)},
+		{"ann", nil, "cy", "dee"},
+		{1.5, 2.0, nil, -0.25},
+	}
+	if !reflect.DeepEqual(cols, expected) {
+		t.Errorf("expected %#v, got %#v\n", expected, cols)
+	}
+}
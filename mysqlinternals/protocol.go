@@ -0,0 +1,128 @@
+// sqlinternals for github.com/go-sql-driver/mysql - retrieve column metadata from sql.*Row / sql.*Rows
+//
+// Copyright 2013 Arne Hormann. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mysqlinternals
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// ProtocolDiff describes how the metadata of a column differs between the text
+// and the binary protocol, see CompareProtocols.
+type ProtocolDiff struct {
+	// Index is the position of the column in the result
+	Index int
+	// Text and Binary are the column as reported with the text and the binary protocol
+	Text, Binary Column
+	// Differences names the differing properties:
+	// "MysqlType", "Flags", "ReflectGoType" and "DriverValueKind"
+	Differences []string
+}
+
+// CompareProtocols runs query with db.Query using the text protocol and as a prepared
+// statement using the binary protocol and reports the columns with different metadata.
+//
+// The query is executed TWICE. Only statements starting with SELECT, WITH, SHOW, TABLE
+// or VALUES are accepted, but side effects of the query itself, e.g. SELECT ... FOR UPDATE
+// or calls of functions modifying data, happen twice.
+//
+// The driver.Value delivered for a column depends on the protocol, so DriverValueKind
+// is compared with parseTime unset, e.g. SELECT 1 returns []byte for the text and int64
+// for the binary protocol. Query arguments are only sent with the text protocol
+// if interpolateParams=true is set in the DSN; without it, args must be empty.
+// The columns of both results are read before the rows are closed, the results are discarded.
+func CompareProtocols(db *sql.DB, query string, args ...interface{}) ([]ProtocolDiff, error) {
+	const (
+		errNotQuery = mysqlError("CompareProtocols: only SELECT, WITH, SHOW, TABLE and VALUES statements are run twice")
+		errNotText  = mysqlError("CompareProtocols: the query did not use the text protocol, set interpolateParams=true for args")
+		errColumns  = mysqlError("CompareProtocols: the protocols returned different column counts")
+	)
+	if !isQuery(query) {
+		return nil, errNotQuery
+	}
+	text, err := protocolColumns(db.Query(query, args...))
+	if err != nil {
+		return nil, err
+	}
+	if text.binary {
+		return nil, errNotText
+	}
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	binary, err := protocolColumns(stmt.Query(args...))
+	if err != nil {
+		return nil, err
+	}
+	if len(text.cols) != len(binary.cols) {
+		return nil, errColumns
+	}
+	return compareProtocolColumns(text.cols, binary.cols), nil
+}
+
+// isQuery reports whether the statement starts with a keyword of a statement returning rows.
+// Leading parentheses are skipped, statements starting with a comment are rejected.
+func isQuery(statement string) bool {
+	statement = strings.TrimLeft(statement, " \t\r\n(")
+	keyword := statement
+	if end := strings.IndexAny(statement, " \t\r\n("); end >= 0 {
+		keyword = statement[:end]
+	}
+	switch strings.ToUpper(keyword) {
+	case "SELECT", "WITH", "SHOW", "TABLE", "VALUES":
+		return true
+	}
+	return false
+}
+
+// protocolResult holds the columns of a result and its protocol
+type protocolResult struct {
+	cols   []Column
+	binary bool
+}
+
+// protocolColumns reads the columns and the protocol of rows and closes them.
+func protocolColumns(rows *sql.Rows, err error) (protocolResult, error) {
+	if err != nil {
+		return protocolResult{}, err
+	}
+	defer rows.Close()
+	cols, binary, err := ColumnsAndProtocol(rows)
+	return protocolResult{cols: cols, binary: binary}, err
+}
+
+// compareProtocolColumns compares the columns of the same query in the text and the binary protocol.
+func compareProtocolColumns(text, binary []Column) []ProtocolDiff {
+	var diffs []ProtocolDiff
+	for i := range text {
+		t, b := text[i], binary[i]
+		var differences []string
+		if t.MysqlType() != b.MysqlType() {
+			differences = append(differences, "MysqlType")
+		}
+		// all implementations of Column are mysqlField
+		tf, _ := t.(mysqlField)
+		bf, _ := b.(mysqlField)
+		if tf.flags != bf.flags {
+			differences = append(differences, "Flags")
+		}
+		if t.ReflectGoTypeOrBytes() != b.ReflectGoTypeOrBytes() {
+			differences = append(differences, "ReflectGoType")
+		}
+		if t.DriverValueKind(false, false) != b.DriverValueKind(true, false) {
+			differences = append(differences, "DriverValueKind")
+		}
+		if differences != nil {
+			diffs = append(diffs, ProtocolDiff{Index: i, Text: t, Binary: b, Differences: differences})
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,310 @@
+// sqlinternals for github.com/go-sql-driver/mysql - retrieve column metadata from sql.*Row / sql.*Rows
+//
+// Copyright 2013 Arne Hormann. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mysqlinternals
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+// ScanRowInto scans the current row of rows into the struct dst points to.
+//
+// Columns are matched to the exported fields by their `db:"..."` tag or the field name
+// derived from the column name by FieldNamer. Tags qualified with the table like
+// `db:"orders.id"` take precedence, they distinguish columns of joined tables sharing a name.
+// Each field receives at most one column, columns without a field are skipped.
+// The values are scanned into nullable types from ReflectSqlType and converted to the
+// field types; NULL requires a pointer, slice, interface{} or sql.Scanner field.
+// Fields that are neither pointers nor sql.Scanner implementations are required,
+// it returns an error if no column is scanned into them.
+func ScanRowInto(rows *sql.Rows, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return mysqlError("ScanRowInto: dst must be a non-nil pointer to a struct")
+	}
+	v = v.Elem()
+	cols, err := Columns(rows)
+	if err != nil {
+		return err
+	}
+	structType := v.Type()
+	fields := map[string]int{}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+		name := field.Tag.Get("db")
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = i
+	}
+	scanned := make([]bool, structType.NumField())
+	fieldIndex := make([]int, len(cols))
+	targets := make([]interface{}, len(cols))
+	for i, col := range cols {
+		fieldIndex[i] = -1
+		for _, name := range []string{col.TableName() + "." + col.Name(), col.Name(), FieldNamer(col.Name())} {
+			if j, ok := fields[name]; ok && !scanned[j] {
+				fieldIndex[i] = j
+				scanned[j] = true
+				break
+			}
+		}
+		if fieldIndex[i] < 0 {
+			// not wanted, but Scan needs a destination
+			targets[i] = new(interface{})
+			continue
+		}
+		targets[i] = reflect.New(scanTarget(col)).Interface()
+	}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.PkgPath != "" || scanned[i] || field.Type.Kind() == reflect.Ptr ||
+			reflect.PtrTo(field.Type).Implements(typeScanner) {
+			continue
+		}
+		return mysqlError("ScanRowInto: no column for required field " + field.Name)
+	}
+	if err := rows.Scan(targets...); err != nil {
+		return err
+	}
+	for i, col := range cols {
+		if fieldIndex[i] < 0 {
+			continue
+		}
+		value, err := scannedValue(col, targets[i])
+		if err != nil {
+			return err
+		}
+		field := v.Field(fieldIndex[i])
+		if err := assignValue(field, value); err != nil {
+			return mysqlError("ScanRowInto: field " + structType.Field(fieldIndex[i]).Name +
+				" for column " + strconv.Quote(col.Name()) + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+// scannedValue returns the value scanned into target, a pointer to a scanTarget of col,
+// as a driver.Value or the result of ConvertValue. NULL is returned as nil.
+func scannedValue(col Column, target interface{}) (interface{}, error) {
+	value := reflect.ValueOf(target).Elem().Interface()
+	if valuer, ok := value.(driver.Valuer); ok {
+		var err error
+		if value, err = valuer.Value(); err != nil {
+			return nil, err
+		}
+	} else if b, ok := value.([]byte); ok && b == nil {
+		value = nil
+	}
+	return col.ConvertValue(value)
+}
+
+// ReadColumnar reads all remaining rows and returns the values column by column,
+// cols[i] holds the values of the column named names[i] in the order of the rows.
+//
+// Non-NULL values have the type of ReflectGoType, except for DECIMAL and TIME values kept as
+// strings without loss and YEAR values as int64. Values of columns without a Go type,
+// e.g. ENUM and GEOMETRY, are kept as string or []byte. NULL values are nil.
+// It doesn't close rows.
+func ReadColumnar(rows *sql.Rows) (names []string, cols [][]interface{}, err error) {
+	columns, err := Columns(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	names = make([]string, len(columns))
+	cols = make([][]interface{}, len(columns))
+	valueTypes := make([]reflect.Type, len(columns))
+	targets := make([]interface{}, len(columns))
+	for i, col := range columns {
+		names[i] = col.Name()
+		valueTypes[i] = columnarType(col)
+		targets[i] = reflect.New(scanTarget(col)).Interface()
+	}
+	for rows.Next() {
+		if err := rows.Scan(targets...); err != nil {
+			return nil, nil, err
+		}
+		for i, col := range columns {
+			value, err := scannedValue(col, targets[i])
+			if err != nil {
+				return nil, nil, err
+			}
+			if value != nil && valueTypes[i] != nil {
+				v := reflect.New(valueTypes[i]).Elem()
+				if err := assignValue(v, value); err != nil {
+					return nil, nil, mysqlError("ReadColumnar: column " + strconv.Quote(col.Name()) + ": " + err.Error())
+				}
+				value = v.Interface()
+			}
+			cols[i] = append(cols[i], value)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	return names, cols, nil
+}
+
+// columnarType returns the type of the values of col in ReadColumnar,
+// nil keeps the values scanned into the scanTarget.
+func columnarType(col Column) reflect.Type {
+	switch {
+	case col.IsDecimal(), builtinTypeOf(col) == "TIME":
+		return typeString
+	case builtinTypeOf(col) == "YEAR":
+		return typeInt64
+	}
+	t, err := col.ReflectGoType()
+	if err != nil {
+		return nil
+	}
+	return t
+}
+
+// scanTarget returns the nullable type values of col are scanned into by ScanRowInto.
+func scanTarget(col Column) reflect.Type {
+	switch {
+	case col.IsInteger() && col.IsUnsigned() && builtinTypeOf(col) == "BIGINT",
+		builtinTypeOf(col) == "TIME", col.Category() == CategoryEnum, col.Category() == CategorySet:
+		// sql.NullInt64 overflows, mysql.NullTime can't parse durations,
+		// the strings are converted with ConvertValue and assignValue
+		return typeNullString
+	case builtinTypeOf(col) == "YEAR":
+		return typeNullInt64
+	}
+	if t, err := col.ReflectSqlType(true); err == nil {
+		return t
+	}
+	return typeBytes
+}
+
+// assignValue stores value, a driver.Value or the result of ConvertValue, in the field v.
+func assignValue(v reflect.Value, value interface{}) error {
+	if scanner, ok := v.Addr().Interface().(sql.Scanner); ok {
+		return scanner.Scan(value)
+	}
+	if value == nil {
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		return mysqlError("NULL can't be stored in " + v.Type().String())
+	}
+	if v.Kind() == reflect.Ptr {
+		elem := reflect.New(v.Type().Elem())
+		if err := assignValue(elem.Elem(), value); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+	src := reflect.ValueOf(value)
+	if src.Type().AssignableTo(v.Type()) {
+		v.Set(src)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch value := value.(type) {
+		case int64:
+			return setInt(v, value)
+		case string:
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return err
+			}
+			return setInt(v, n)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch value := value.(type) {
+		case int64:
+			if value < 0 {
+				return mysqlError(strconv.FormatInt(value, 10) + " overflows " + v.Type().String())
+			}
+			return setUint(v, uint64(value))
+		case string:
+			n, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return err
+			}
+			return setUint(v, n)
+		}
+	case reflect.Float32, reflect.Float64:
+		switch value := value.(type) {
+		case float64:
+			return setFloat(v, value)
+		case int64:
+			return setFloat(v, float64(value))
+		case string:
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return err
+			}
+			return setFloat(v, n)
+		}
+	case reflect.String:
+		if b, ok := value.([]byte); ok {
+			v.SetString(string(b))
+			return nil
+		}
+		if src.Kind() == reflect.String {
+			// named string types, e.g. from EnumGoType
+			v.SetString(src.String())
+			return nil
+		}
+	case reflect.Slice:
+		if s, ok := value.(string); ok && v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte(s))
+			return nil
+		}
+	case reflect.Struct:
+		// DECIMAL values without fraction, see ReflectGoType
+		if n, ok := v.Addr().Interface().(*big.Int); ok {
+			if s, ok := value.(string); ok {
+				if _, ok := n.SetString(s, 10); ok {
+					return nil
+				}
+				return mysqlError(strconv.Quote(s) + " is not an integer")
+			}
+		}
+	}
+	return mysqlError("can't store " + src.Type().String() + " in " + v.Type().String())
+}
+
+func setInt(v reflect.Value, n int64) error {
+	if v.OverflowInt(n) {
+		return mysqlError(strconv.FormatInt(n, 10) + " overflows " + v.Type().String())
+	}
+	v.SetInt(n)
+	return nil
+}
+
+func setUint(v reflect.Value, n uint64) error {
+	if v.OverflowUint(n) {
+		return mysqlError(strconv.FormatUint(n, 10) + " overflows " + v.Type().String())
+	}
+	v.SetUint(n)
+	return nil
+}
+
+func setFloat(v reflect.Value, n float64) error {
+	if v.OverflowFloat(n) {
+		return mysqlError(strconv.FormatFloat(n, 'g', -1, 64) + " overflows " + v.Type().String())
+	}
+	v.SetFloat(n)
+	return nil
+}
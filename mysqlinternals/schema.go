@@ -0,0 +1,230 @@
+// sqlinternals for github.com/go-sql-driver/mysql - retrieve column metadata from sql.*Row / sql.*Rows
+//
+// Copyright 2013 Arne Hormann. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mysqlinternals
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// errNilConn is returned by the lookups in information_schema for a nil *sql.Conn
+const errNilConn = mysqlError("information_schema lookup needs a non-nil *sql.Conn")
+
+// schemaColumn reads fields of the column from information_schema.COLUMNS into dest.
+// It returns false if the column has no table or was not found.
+// The table is searched in the current database of conn.
+func schemaColumn(conn *sql.Conn, f mysqlField, fields string, dest ...interface{}) (bool, error) {
+	if conn == nil {
+		return false, errNilConn
+	}
+	if f.tableName == "" {
+		return false, nil
+	}
+	err := conn.QueryRowContext(context.Background(),
+		"SELECT "+fields+" FROM information_schema.COLUMNS"+
+			" WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?",
+		f.tableName, f.name,
+	).Scan(dest...)
+	switch err {
+	case nil:
+		return true, nil
+	case sql.ErrNoRows:
+		return false, nil
+	}
+	return false, err
+}
+
+// generated column with expression
+func (f mysqlField) IsGenerated(conn *sql.Conn) (bool, string, error) {
+	var extra, expression string
+	found, err := schemaColumn(conn, f, "EXTRA, GENERATION_EXPRESSION", &extra, &expression)
+	if !found {
+		return false, "", err
+	}
+	// "DEFAULT_GENERATED" in EXTRA is used for default values
+	if !strings.Contains(extra, "VIRTUAL GENERATED") && !strings.Contains(extra, "STORED GENERATED") {
+		return false, "", nil
+	}
+	return true, expression, nil
+}
+
+// default value from information_schema
+func (f mysqlField) ColumnDefault(conn *sql.Conn) (string, bool, error) {
+	var value sql.NullString
+	found, err := schemaColumn(conn, f, "COLUMN_DEFAULT", &value)
+	if !found || !value.Valid {
+		return "", false, err
+	}
+	return value.String, true, nil
+}
+
+// spatial reference system id of geometry columns
+func (f mysqlField) SRID(conn *sql.Conn) (uint32, bool, error) {
+	if f.Category() != CategoryGeometry {
+		return 0, false, nil
+	}
+	var srid sql.NullInt64
+	found, err := schemaColumn(conn, f, "SRS_ID", &srid)
+	if !found || !srid.Valid {
+		return 0, false, err
+	}
+	return uint32(srid.Int64), true, nil
+}
+
+// get a type declaration with the SRID of geometry columns
+func (f mysqlField) MysqlDeclarationWithSRID(conn *sql.Conn, args ...interface{}) (string, error) {
+	decl, err := f.MysqlDeclaration(args...)
+	if err != nil {
+		return "", err
+	}
+	srid, ok, err := f.SRID(conn)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		decl += " SRID " + strconv.FormatUint(uint64(srid), 10)
+	}
+	return decl, nil
+}
+
+// SchemaColumn is implemented by the columns returned by ColumnsWithSchema.
+type SchemaColumn interface {
+	Column
+	// DefaultValue returns the default value of the column in information_schema,
+	// ok is false if it has none or the column was not found there.
+	DefaultValue() (value string, ok bool)
+	// FromSchema reports whether the attributes were read from information_schema
+	// instead of the flags of the result.
+	FromSchema() bool
+	// OnUpdateCurrentTimestamp reports whether the column is declared with
+	// ON UPDATE CURRENT_TIMESTAMP, e.g. TIMESTAMP and DATETIME columns updated automatically.
+	OnUpdateCurrentTimestamp() bool
+	// NoDefaultValue reports whether the column is NOT NULL without a default value,
+	// so inserts must provide a value unless it is AUTO_INCREMENT or generated.
+	// It is false for columns not found in information_schema.
+	NoDefaultValue() bool
+}
+
+// schemaField is a mysqlField with the attributes from information_schema
+type schemaField struct {
+	mysqlField
+	defaultValue sql.NullString
+	fromSchema   bool
+	onUpdateNow  bool
+	generated    bool
+}
+
+func (f schemaField) DefaultValue() (string, bool) {
+	return f.defaultValue.String, f.defaultValue.Valid
+}
+
+func (f schemaField) FromSchema() bool {
+	return f.fromSchema
+}
+
+func (f schemaField) OnUpdateCurrentTimestamp() bool {
+	return f.onUpdateNow
+}
+
+func (f schemaField) NoDefaultValue() bool {
+	return f.fromSchema && f.IsNotNull() && !f.defaultValue.Valid && !f.IsAutoIncrement() && !f.generated
+}
+
+// flags read from information_schema by ColumnsWithSchema
+const schemaFlags = flagNotNULL | flagPriKey | flagUniqueKey | flagMultipleKey | flagAutoIncrement
+
+// ColumnsWithSchema retrieves the columns of rows like Columns, but takes NOT NULL,
+// the key flags and AUTO_INCREMENT from information_schema.COLUMNS for columns of tables
+// and adds their default values. The columns implement SchemaColumn.
+//
+// The tables are searched in the current database of conn with one query per table;
+// conn must not be the connection rows were read from and must not be nil.
+// The driver only keeps the names used in the query, so aliases are not detected:
+// tables and columns must not be aliased and tables of other databases must not be used.
+// A column aliased to the name of another column of its table, a table alias or a table
+// of another database sharing the name of a table in the current database silently take
+// the attributes of the wrong column. Columns of expressions and columns not found keep
+// the flags of the result. Only the strongest key of a column is known in
+// information_schema, it is reported as the only one.
+func ColumnsWithSchema(conn *sql.Conn, rows *sql.Rows) ([]Column, error) {
+	if conn == nil {
+		return nil, errNilConn
+	}
+	cols, err := ColumnsForCurrentResultSet(rows)
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]schemaField, len(cols))
+	for i, col := range cols {
+		// all implementations of Column are mysqlField
+		fields[i].mysqlField, _ = col.(mysqlField)
+	}
+	for _, table := range SourceTables(cols) {
+		if err := readSchemaTable(conn, table, fields); err != nil {
+			return nil, err
+		}
+	}
+	schemaCols := make([]Column, len(fields))
+	for i, f := range fields {
+		schemaCols[i] = f
+	}
+	return schemaCols, nil
+}
+
+// readSchemaTable sets the attributes of the fields of table from information_schema.
+func readSchemaTable(conn *sql.Conn, table string, fields []schemaField) error {
+	rows, err := conn.QueryContext(context.Background(),
+		"SELECT COLUMN_NAME, IS_NULLABLE, COLUMN_KEY, EXTRA, COLUMN_DEFAULT FROM information_schema.COLUMNS"+
+			" WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?",
+		table,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, nullable, key, extra string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&name, &nullable, &key, &extra, &defaultValue); err != nil {
+			return err
+		}
+		flags := fieldFlag(0)
+		if nullable == "NO" {
+			flags |= flagNotNULL
+		}
+		switch key {
+		case "PRI":
+			flags |= flagPriKey
+		case "UNI":
+			flags |= flagUniqueKey
+		case "MUL":
+			flags |= flagMultipleKey
+		}
+		if strings.Contains(extra, "auto_increment") {
+			flags |= flagAutoIncrement
+		}
+		// MariaDB reports "on update current_timestamp()"
+		onUpdateNow := strings.Contains(strings.ToLower(extra), "on update current_timestamp")
+		generated := strings.Contains(extra, "VIRTUAL GENERATED") || strings.Contains(extra, "STORED GENERATED")
+		for i := range fields {
+			f := &fields[i]
+			if f.tableName != table || !strings.EqualFold(f.name, name) {
+				continue
+			}
+			f.flags = f.flags&^schemaFlags | flags
+			f.defaultValue = defaultValue
+			f.fromSchema = true
+			f.onUpdateNow = onUpdateNow
+			f.generated = generated
+		}
+	}
+	return rows.Err()
+}
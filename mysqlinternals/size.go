@@ -0,0 +1,51 @@
+// sqlinternals for github.com/go-sql-driver/mysql - retrieve column metadata from sql.*Row / sql.*Rows
+//
+// Copyright 2013 Arne Hormann. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mysqlinternals
+
+// UnboundedColumnBytes is the size EstimateRowBytes assumes for a value
+// when the maximum length of its column is larger or unknown,
+// e.g. for TEXT, BLOB and JSON columns.
+var UnboundedColumnBytes int64 = 4096
+
+// EstimateRowBytes estimates the memory needed for the values of one row.
+// Numeric and temporal columns count with the size of their Go type,
+// textual and binary columns with their maximum length, capped at UnboundedColumnBytes.
+// The result helps to decide between streaming and buffering a result, it is not exact.
+func EstimateRowBytes(cols []Column) int64 {
+	var total int64
+	for _, col := range cols {
+		total += estimateValueBytes(col)
+	}
+	return total
+}
+
+func estimateValueBytes(col Column) int64 {
+	if length, ok := col.MaxLength(); ok {
+		if length > UnboundedColumnBytes {
+			return UnboundedColumnBytes
+		}
+		return length
+	}
+	if precision, _, ok := col.DecimalSize(); ok {
+		// digits, sign and decimal point as text
+		return precision + 2
+	}
+	switch col.Category() {
+	case CategoryInteger, CategoryFloat, CategoryTemporal:
+		if t, err := col.ReflectGoType(); err == nil {
+			return int64(t.Size())
+		}
+	case CategoryBit:
+		// at most 64 bits
+		return 8
+	case CategoryNull:
+		return 0
+	}
+	return UnboundedColumnBytes
+}
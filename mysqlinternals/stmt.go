@@ -0,0 +1,88 @@
+// sqlinternals for github.com/go-sql-driver/mysql - retrieve column metadata from sql.*Row / sql.*Rows
+//
+// Copyright 2013 Arne Hormann. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mysqlinternals
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"unsafe"
+)
+
+// keep mysqlStmt in sync with the struct in github.com/go-sql-driver/mysql/statement.go
+type mysqlStmt struct {
+	mc         *mysqlConn
+	id         uint32
+	paramCount int
+	columns    []mysqlField
+}
+
+// validation results by type of the driver.Stmt, guarded by layoutMutex
+var stmtChecks = map[reflect.Type]bool{}
+
+// checkStmt validates the layout of the driver's mysqlStmt once per type.
+func checkStmt(stmtType reflect.Type) bool {
+	layoutMutex.RLock()
+	valid, checked := stmtChecks[stmtType]
+	layoutMutex.RUnlock()
+	if checked {
+		return valid
+	}
+	elemType := stmtType.Elem()
+	valid = canConvert(elemType, reflect.TypeOf(mysqlStmt{}))
+	if valid {
+		colsField, _ := elemType.FieldByName("columns")
+		valid = canConvert(colsField.Type.Elem(), reflect.TypeOf(mysqlField{}))
+	}
+	if !valid {
+		logf("mysqlinternals: unsupported layout of %s", stmtType)
+	}
+	layoutMutex.Lock()
+	stmtChecks[stmtType] = valid
+	layoutMutex.Unlock()
+	return valid
+}
+
+// ColumnsFromStmt retrieves the result columns of a statement prepared by
+// github.com/go-sql-driver/mysql, e.g. for drivers wrapping it.
+//
+// It only supports the driver's mysqlStmt and reads the column definitions it keeps
+// from the response to COM_STMT_PREPARE. Driver versions discarding them, i.e. without
+// the field columns in mysqlStmt, return ErrNoColumnMetadata.
+// ErrNoResultSet is only returned for statements without a result set.
+// The layout of mysqlStmt is validated like that of the driver's rows.
+func ColumnsFromStmt(stmt driver.Stmt) (cols []Column, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			cols, err = nil, recoveredError("ColumnsFromStmt", stmt, r)
+		}
+	}()
+	if stmt == nil {
+		return nil, columnsError("ColumnsFromStmt", stmt, StageNil)
+	}
+	stmtType := reflect.TypeOf(stmt)
+	if stmtType.Kind() != reflect.Ptr || stmtType.Elem().Kind() != reflect.Struct ||
+		stmtType.Elem().Name() != "mysqlStmt" || reflect.ValueOf(stmt).IsNil() {
+		return nil, columnsError("ColumnsFromStmt", stmt, StageNotMysql)
+	}
+	if _, ok := stmtType.Elem().FieldByName("columns"); !ok {
+		// the driver does not keep the column definitions
+		return nil, ErrNoColumnMetadata
+	}
+	if !checkStmt(stmtType) {
+		return nil, columnsError("ColumnsFromStmt", stmt, StageLayoutMismatch)
+	}
+	fields := (*mysqlStmt)((unsafe.Pointer)(reflect.ValueOf(stmt).Pointer())).columns
+	if len(fields) == 0 {
+		return nil, ErrNoResultSet
+	}
+	if len(fields) > cap(fields) {
+		return nil, columnsError("ColumnsFromStmt", stmt, StageColumnCountMismatch)
+	}
+	return fieldColumns(fields), nil
+}
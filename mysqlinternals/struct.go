@@ -0,0 +1,271 @@
+// sqlinternals for github.com/go-sql-driver/mysql - retrieve column metadata from sql.*Row / sql.*Rows
+//
+// Copyright 2013 Arne Hormann. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mysqlinternals
+
+import (
+	"database/sql"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// StructType creates a struct type with one field per column.
+//
+// The field types are retrieved with ReflectSqlType(forceNullable), the field names are
+// derived from the column names by FieldNamer and the column names are kept in a `db:"..."` tag.
+// It returns an error if FieldNamer does not return an exported identifier.
+// The field indices match the column indices. Pointers to the fields can be passed to Scan,
+// except for types Scan can't convert the driver's values to: big.Int of NOT NULL DECIMAL,
+// []bool of NOT NULL BIT, []string of SET and time.Time of NOT NULL YEAR and TIME columns;
+// DATE, DATETIME and TIMESTAMP columns also require parseTime=true in the DSN.
+// ScanRowInto converts the values of DECIMAL columns into big.Int fields.
+func StructType(cols []Column, forceNullable bool) (reflect.Type, error) {
+	fields := make([]reflect.StructField, len(cols))
+	used := make(map[string]bool, len(cols))
+	for i, col := range cols {
+		fieldType, err := col.ReflectSqlType(forceNullable)
+		if err != nil {
+			return nil, err
+		}
+		name := FieldNamer(col.Name())
+		if !token.IsIdentifier(name) || !token.IsExported(name) {
+			return nil, mysqlError("StructType: FieldNamer returned no exported identifier for " + strconv.Quote(col.Name()))
+		}
+		for base, n := name, 2; used[name]; n++ {
+			// columns of joined tables may share a name
+			name = base + strconv.Itoa(n)
+		}
+		used[name] = true
+		fields[i] = reflect.StructField{
+			Name: name,
+			Type: fieldType,
+			Tag:  reflect.StructTag("db:" + strconv.Quote(col.Name())),
+		}
+	}
+	return reflect.StructOf(fields), nil
+}
+
+// FieldNamer converts column names to the names of exported struct fields
+// in StructType and ValidateStruct.
+// The default converts them to CamelCase, drops all characters except letters and digits
+// and prefixes names not starting with an uppercase letter with "X", e.g. "user_id" to "UserId".
+var FieldNamer = exportedName
+
+// exportedName converts a column name to an exported Go identifier in CamelCase.
+func exportedName(columnName string) string {
+	var name []rune
+	upper := true
+	for _, r := range columnName {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		name = append(name, r)
+	}
+	if len(name) == 0 || !unicode.IsUpper(name[0]) {
+		// identifiers must start with an uppercase letter to be exported
+		return "X" + string(name)
+	}
+	return string(name)
+}
+
+// ReflectSqlTypeColumns returns the result of ReflectSqlType for each column.
+//
+// Columns named in forceNullableNames are treated as nullable regardless of IsNotNull,
+// e.g. for columns of the optional side of an outer join.
+func ReflectSqlTypeColumns(cols []Column, forceNullableNames []string) ([]reflect.Type, error) {
+	forceNullable := make(map[string]bool, len(forceNullableNames))
+	for _, name := range forceNullableNames {
+		forceNullable[name] = true
+	}
+	types := make([]reflect.Type, len(cols))
+	for i, col := range cols {
+		t, err := col.ReflectSqlType(forceNullable[col.Name()])
+		if err != nil {
+			return nil, err
+		}
+		types[i] = t
+	}
+	return types, nil
+}
+
+var typeScanner = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// ValidateStruct checks that the struct dst (or a pointer to it) can receive the columns.
+//
+// Columns are matched to the exported fields by their `db:"..."` tag or the field name
+// derived from the column name by FieldNamer. Each column must have a field
+// and each exported field must have a column. The field types must be able to hold
+// the values of ReflectSqlType(false) or ReflectGoType for NOT NULL columns;
+// pointers, sql.Scanner implementations and interface{} are accepted for any column.
+// The error describes the first incompatible field.
+func ValidateStruct(cols []Column, dst interface{}) error {
+	structType := reflect.TypeOf(dst)
+	if structType != nil && structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType == nil || structType.Kind() != reflect.Struct {
+		return mysqlError("ValidateStruct: dst must be a struct or a pointer to a struct")
+	}
+	fields := map[string]reflect.StructField{}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+		name := field.Tag.Get("db")
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field
+	}
+	if len(fields) != len(cols) {
+		return mysqlError("ValidateStruct: " + strconv.Itoa(len(cols)) + " columns, but " +
+			strconv.Itoa(len(fields)) + " fields in " + structType.String())
+	}
+	for _, col := range cols {
+		field, ok := fields[col.Name()]
+		if !ok {
+			field, ok = fields[FieldNamer(col.Name())]
+		}
+		if !ok {
+			return mysqlError("ValidateStruct: no field for column " + strconv.Quote(col.Name()))
+		}
+		if problem := scanProblem(col, field.Type); problem != "" {
+			return mysqlError("ValidateStruct: field " + field.Name + " for column " +
+				strconv.Quote(col.Name()) + ": " + problem)
+		}
+	}
+	return nil
+}
+
+// scanProblem describes why values of col can't be scanned into t, it is empty if they can.
+func scanProblem(col Column, t reflect.Type) string {
+	switch {
+	case t.Kind() == reflect.Interface && t.NumMethod() == 0,
+		reflect.PtrTo(t).Implements(typeScanner):
+		return ""
+	case t.Kind() == reflect.Ptr:
+		// nil for NULL
+		t = t.Elem()
+	case !col.IsNotNull():
+		if sqlType, err := col.ReflectSqlType(false); err == nil && sqlType == t {
+			return ""
+		}
+		return "nullable column needs a nullable type, not " + t.String()
+	}
+	goType, err := col.ReflectGoType()
+	if err != nil {
+		return err.Error()
+	}
+	switch col.Category() {
+	case CategoryText, CategoryBlob, CategoryEnum, CategorySet, CategoryJSON, CategoryDecimal:
+		// the driver delivers these as bytes
+		if t.Kind() == reflect.String || t == typeBytes {
+			return ""
+		}
+	}
+	if goType == t || fitsInto(goType, t) {
+		return ""
+	}
+	return "type " + t.String() + " does not match " + goType.String()
+}
+
+// fitsInto reports whether all values of the numeric type from fit into the numeric type to.
+func fitsInto(from, to reflect.Type) bool {
+	signed := func(k reflect.Kind) bool { return k >= reflect.Int && k <= reflect.Int64 }
+	unsigned := func(k reflect.Kind) bool { return k >= reflect.Uint && k <= reflect.Uintptr }
+	floating := func(k reflect.Kind) bool { return k == reflect.Float32 || k == reflect.Float64 }
+	fk, tk := from.Kind(), to.Kind()
+	switch {
+	case signed(fk) && signed(tk), unsigned(fk) && unsigned(tk), floating(fk) && floating(tk):
+		return from.Size() <= to.Size()
+	case unsigned(fk) && signed(tk):
+		return from.Size() < to.Size()
+	}
+	return false
+}
+
+// CSVHeader returns the header row of a CSV export of the columns.
+//
+// Duplicate column names, e.g. from joins, get the suffix "_2", "_3" and so on;
+// the first suffix not used by another name is taken, so the names are unique.
+// If withTypes is set, each name is annotated with its MysqlType like "price:DECIMAL".
+func CSVHeader(cols []Column, withTypes bool) []string {
+	header := make([]string, len(cols))
+	seen := make(map[string]bool, len(cols))
+	for i, col := range cols {
+		name := col.Name()
+		for base, n := name, 2; seen[name]; n++ {
+			name = base + "_" + strconv.Itoa(n)
+		}
+		seen[name] = true
+		if withTypes {
+			name += ":" + col.MysqlType()
+		}
+		header[i] = name
+	}
+	return header
+}
+
+// SourceTables returns the distinct non-empty TableName of the columns in the order of
+// their first appearance. Expressions have no table and are skipped.
+// Like TableName, it returns the aliases used in the query instead of the table names.
+func SourceTables(cols []Column) []string {
+	var tables []string
+	seen := make(map[string]bool, len(cols))
+	for _, col := range cols {
+		table := col.TableName()
+		if table == "" || seen[table] {
+			continue
+		}
+		seen[table] = true
+		tables = append(tables, table)
+	}
+	return tables
+}
+
+// FilterColumns returns the columns for which pred returns true, keeping their order.
+// The predicates of Column can be passed as method expressions,
+// e.g. FilterColumns(cols, Column.IsKeyPart), Column.IsText or Column.IsNumber.
+func FilterColumns(cols []Column, pred func(Column) bool) []Column {
+	var filtered []Column
+	for _, col := range cols {
+		if pred(col) {
+			filtered = append(filtered, col)
+		}
+	}
+	return filtered
+}
+
+// Placeholders returns a parenthesized list of one placeholder per column like "(?, ?, ?)",
+// e.g. for the VALUES of an INSERT statement matching ColumnNameList.
+func Placeholders(cols []Column) string {
+	if len(cols) == 0 {
+		return "()"
+	}
+	return "(?" + strings.Repeat(", ?", len(cols)-1) + ")"
+}
+
+// ColumnNameList returns the names of the columns quoted with backticks and separated
+// by commas like "`id`, `name`". Backticks in names are escaped by doubling them.
+func ColumnNameList(cols []Column) string {
+	names := make([]string, len(cols))
+	for i, col := range cols {
+		names[i] = "`" + strings.Replace(col.Name(), "`", "``", -1) + "`"
+	}
+	return strings.Join(names, ", ")
+}
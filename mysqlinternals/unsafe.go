@@ -9,10 +9,13 @@
 package mysqlinternals
 
 import (
+	"database/sql"
 	"database/sql/driver"
 	"fmt"
+	"math"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"unsafe"
 
 	"github.com/arnehormann/sqlinternals"
@@ -38,6 +41,9 @@ const (
 	fieldTypeVarChar
 	fieldTypeBit
 )
+const (
+	fieldTypeVector byte = 0xf2
+)
 const (
 	fieldTypeJSON byte = iota + 0xf5
 	fieldTypeNewDecimal
@@ -77,20 +83,22 @@ const (
 type mysqlField struct {
 	tableName string
 	name      string
+	length    uint32
 	flags     fieldFlag
 	fieldType byte
 	decimals  byte
+	charSet   uint8
 }
 
 type resultSet struct {
-	columns []mysqlField
+	columns     []mysqlField
 	columnNames []string
-	done    bool
+	done        bool
 }
 
 type mysqlRows struct {
-	mc *mysqlConn
-	rs resultSet
+	mc     *mysqlConn
+	rs     resultSet
 	finish func()
 }
 
@@ -111,28 +119,90 @@ func (e mysqlError) Error() string {
 }
 
 const (
-	errUnexpectedNil  = mysqlError("wrong argument, rows must not be nil")
 	errUnexpectedType = mysqlError("wrong argument, must be *mysql.mysqlRows")
 	rowtypeBinary     = "binaryRows"
 	rowtypeText       = "textRows"
 	rowtypeEmpty      = "emptyRows"
 )
 
+// Layout provides access to the metadata of a validated driver.Rows implementation.
+type Layout struct {
+	// Columns retrieves the columns of rows, create them with ColumnInfo.Column.
+	Columns func(rows driver.Rows) []Column
+	// IsBinary reports whether rows use the binary protocol.
+	IsBinary func(rows driver.Rows) bool
+	// fields retrieves the fields without copying them, only set for go-sql-driver
+	fields func(rows driver.Rows) []mysqlField
+}
+
+// layoutCheck stores the result of a layout validation.
+type layoutCheck struct {
+	layout Layout
+	err    error
+}
+
 var (
-	// populate the offset only once
-	initMutex      sync.Mutex
-	failedInit     bool
-	structsChecked bool
+	layoutMutex sync.RWMutex
+	// validators by type name of the struct the driver.Rows point to
+	layoutValidators = map[string]func(reflect.Type) (Layout, error){}
+	// validation results by type of the driver.Rows, each type is only validated once
+	layoutChecks = map[reflect.Type]layoutCheck{}
+	// []reflect.Type of the validated textRows and binaryRows for ColumnsUnchecked,
+	// replaced on changes under layoutMutex and read without locking
+	uncheckedTypes atomic.Value
 )
 
+func init() {
+	RegisterLayout(rowtypeText, mysqlLayout)
+	RegisterLayout(rowtypeBinary, mysqlLayout)
+}
+
+// RegisterLayout enables Columns, IsBinary etc. for the driver.Rows of other drivers.
+//
+// The driver.Rows must be pointers to structs named driverRowsTypeName.
+// validate is called once for each such type with the pointer type and returns an error
+// if the layout is not supported; the Layout is used for all rows of that type.
+// A registration for driverRowsTypeName replaces the previous one for types not validated yet,
+// a nil validate removes it.
+// go-sql-driver's textRows and binaryRows are registered by default.
+func RegisterLayout(driverRowsTypeName string, validate func(reflect.Type) (Layout, error)) {
+	layoutMutex.Lock()
+	if validate == nil {
+		delete(layoutValidators, driverRowsTypeName)
+	} else {
+		layoutValidators[driverRowsTypeName] = validate
+	}
+	layoutMutex.Unlock()
+}
+
+// Logger receives diagnostic messages, e.g. when the internal structures of the driver
+// don't match the expected ones. It does nothing by default and may be set to nil.
+var Logger = func(message string) {}
+
+func logf(format string, args ...interface{}) {
+	if logger := Logger; logger != nil {
+		logger(fmt.Sprintf(format, args...))
+	}
+}
+
+// maxConvertDepth limits the nesting of anonymous structs compared by canConvert,
+// the driver's structs are far less deeply nested.
+const maxConvertDepth = 32
+
 // canConvert returns true if the memory layout and the struct field names of
 // 'from' match those of 'to'.
+// Type names are only compared if both structs are named.
 func canConvert(from, to reflect.Type) bool {
+	return canConvertDepth(from, to, 0)
+}
+
+func canConvertDepth(from, to reflect.Type, depth int) bool {
 	switch {
-	case from.Kind() != reflect.Struct,
+	case from == nil, to == nil, depth > maxConvertDepth,
+		from.Kind() != reflect.Struct,
 		from.Kind() != to.Kind(),
 		from.Size() != to.Size(),
-		from.Name() != to.Name(),
+		!sameName(from, to),
 		from.NumField() != to.NumField():
 		return false
 	}
@@ -142,22 +212,35 @@ func canConvert(from, to reflect.Type) bool {
 			return false
 		}
 		tsf, ttf := sf.Type, tf.Type
-		for done := false; !done; {
+		// sizes are compared as long as the values are stored inline
+		for done, inline := false, true; !done; {
 			k := tsf.Kind()
-			if k != ttf.Kind() {
+			if k != ttf.Kind() || inline && tsf.Size() != ttf.Size() {
 				return false
 			}
 			switch k {
-			case reflect.Array, reflect.Chan, reflect.Map, reflect.Ptr, reflect.Slice:
+			case reflect.Array:
+				if tsf.Len() != ttf.Len() {
+					return false
+				}
+				tsf, ttf = tsf.Elem(), ttf.Elem()
+			case reflect.Chan, reflect.Map, reflect.Ptr, reflect.Slice:
 				tsf, ttf = tsf.Elem(), ttf.Elem()
+				inline = false
 			case reflect.Interface:
 				// don't have to handle matching interfaces here
 				if tsf != ttf {
 					// there are none in our case, so we are extra strict
 					return false
 				}
+				done = true
 			case reflect.Struct:
-				if tsf.Name() != ttf.Name() {
+				if tsf.Name() == "" || ttf.Name() == "" {
+					// anonymous structs are compared by their fields
+					if !canConvertDepth(tsf, ttf, depth+1) {
+						return false
+					}
+				} else if tsf.Name() != ttf.Name() {
 					return false
 				}
 				done = true
@@ -169,18 +252,20 @@ func canConvert(from, to reflect.Type) bool {
 	return true
 }
 
-func initOffsets(rows driver.Rows) error {
+// sameName returns true if the names of a and b match or one of them is anonymous.
+func sameName(a, b reflect.Type) bool {
+	return a.Name() == b.Name() || a.Name() == "" || b.Name() == ""
+}
+
+func initOffsets(argType reflect.Type) error {
 	const (
 		errWrapperMismatch   = mysqlError("unexpected structure of textRows or binaryRows")
 		errRowsMismatch      = mysqlError("unexpected structure of mysqlRows")
 		errResultsetMismatch = mysqlError("unexpected structure of resultSet")
 		errFieldMismatch     = mysqlError("unexpected structure of mysqlField")
+		errFlagsMismatch     = mysqlError("unexpected type of mysqlField.flags")
 	)
 	// make sure mysqlRows is the right type (full certainty is impossible).
-	if rows == nil {
-		return errUnexpectedNil
-	}
-	argType := reflect.TypeOf(rows)
 	if argType.Kind() != reflect.Ptr {
 		return errUnexpectedType
 	}
@@ -194,7 +279,7 @@ func initOffsets(rows driver.Rows) error {
 		return errUnexpectedType
 	}
 	embedded, ok := elemType.FieldByName("mysqlRows")
-	if !ok {
+	if !ok || embedded.Offset != 0 {
 		return errWrapperMismatch
 	}
 	elemType = embedded.Type
@@ -216,46 +301,251 @@ func initOffsets(rows driver.Rows) error {
 		return errResultsetMismatch
 	}
 	// compare mysqlField
-	if !canConvert(colsField.Type.Elem(), reflect.TypeOf(mysqlField{})) {
-		fmt.Printf("=> %#v\n\n", reflect.Zero(colsField.Type.Elem()).Interface())
+	fieldType := colsField.Type.Elem()
+	if !flagsMatch(fieldType) {
+		logf("mysqlinternals: driver uses %#v", reflect.Zero(fieldType).Interface())
+		return errFlagsMismatch
+	}
+	if !canConvert(fieldType, reflect.TypeOf(mysqlField{})) {
+		logf("mysqlinternals: driver uses %#v", reflect.Zero(fieldType).Interface())
 		return errFieldMismatch
 	}
 	return nil
 }
 
-func driverRows(rowOrRows interface{}) (driver.Rows, bool) {
-	if rowOrRows == nil || failedInit {
-		return nil, false
+// flagsMatch reports whether the flags of the driver's mysqlField have the kind and width
+// of fieldFlag. The name of the flags type may differ.
+func flagsMatch(fieldType reflect.Type) bool {
+	if fieldType.Kind() != reflect.Struct {
+		return false
+	}
+	flags, ok := fieldType.FieldByName("flags")
+	return ok && flags.Type.Kind() == reflect.Uint16 && flags.Type.Size() == unsafe.Sizeof(fieldFlag(0))
+}
+
+// isEmptyRows reports whether rows is the driver's value for statements without a result set.
+func isEmptyRows(rows driver.Rows) bool {
+	argType := reflect.TypeOf(rows)
+	return argType.Kind() == reflect.Struct && argType.Name() == rowtypeEmpty
+}
+
+// mysqlLayout validates the layout of go-sql-driver's textRows and binaryRows.
+func mysqlLayout(rowsType reflect.Type) (Layout, error) {
+	if err := initOffsets(rowsType); err != nil {
+		return Layout{}, err
+	}
+	return Layout{
+		Columns:  mysqlColumns,
+		IsBinary: mysqlIsBinary,
+		fields:   mysqlFields,
+	}, nil
+}
+
+// emptyLayout is used for the driver's emptyRows, there is no result set
+var emptyLayout = Layout{
+	Columns:  func(driver.Rows) []Column { return nil },
+	IsBinary: func(driver.Rows) bool { return false },
+}
+
+// InspectStage is the step of the inspection of rows that failed.
+type InspectStage int
+
+const (
+	// the inspection succeeded
+	StageNone InspectStage = iota
+	// the argument is nil
+	StageNil
+	// the argument is not sql.Rows or sql.Row with driver.Rows
+	StageNotRows
+	// the driver.Rows are not from github.com/go-sql-driver/mysql or a registered layout
+	StageNotMysql
+	// the layout of the driver.Rows does not match the expected one
+	StageLayoutMismatch
+	// the number of columns read does not match the number of columns the driver reports
+	StageColumnCountMismatch
+	// reading the columns panicked
+	StagePanic
+	// the names or the order of the columns read differ from the columns the driver reports
+	StageColumnNameMismatch
+	// the layout of the driver.Rows type was not validated yet, see ColumnsUnchecked
+	StageNotValidated
+)
+
+func (s InspectStage) String() string {
+	switch s {
+	case StageNone:
+		return "no error"
+	case StageNil:
+		return "argument is nil"
+	case StageNotRows:
+		return "argument has no driver.Rows"
+	case StageNotMysql:
+		return "driver.Rows have no registered layout"
+	case StageLayoutMismatch:
+		return "driver.Rows have an unsupported layout"
+	case StageColumnCountMismatch:
+		return "column count differs from the driver's"
+	case StagePanic:
+		return "reading the columns panicked"
+	case StageColumnNameMismatch:
+		return "column names differ from the driver's"
+	case StageNotValidated:
+		return "driver.Rows layout was not validated yet"
+	}
+	return "unknown stage"
+}
+
+// ColumnsError describes why the columns of an argument are not available.
+// Retrieve it from the errors of Columns and related functions with errors.As.
+type ColumnsError struct {
+	// Func is the name of the called function
+	Func string
+	// Type is the type of the argument
+	Type string
+	// Stage is the step of the inspection that failed
+	Stage InspectStage
+	// Recovered is the value recovered from the panic for StagePanic
+	Recovered interface{}
+}
+
+func (e *ColumnsError) Error() string {
+	msg := e.Func + " is not available: " + e.Stage.String() + " (" + e.Type + ")"
+	if e.Recovered != nil {
+		msg += fmt.Sprintf(": %v", e.Recovered)
+	}
+	return msg
+}
+
+// Unwrap returns the recovered value if it is an error.
+func (e *ColumnsError) Unwrap() error {
+	err, _ := e.Recovered.(error)
+	return err
+}
+
+// recoveredError converts the value recovered from a panic while reading the columns.
+//
+// Reading the driver's memory through a mismatched layout may panic, e.g. on an index out of range;
+// faults on arbitrary addresses are fatal in Go and can't be recovered.
+func recoveredError(fn string, arg interface{}, recovered interface{}) error {
+	return &ColumnsError{Func: fn, Type: fmt.Sprintf("%T", arg), Stage: StagePanic, Recovered: recovered}
+}
+
+func columnsError(fn string, arg interface{}, stage InspectStage) error {
+	return &ColumnsError{Func: fn, Type: fmt.Sprintf("%T", arg), Stage: stage}
+}
+
+// offset of sql.Rows.rowsi for the fast path of driverRows, hasRowsRowsi is false if it is missing
+var offsetRowsRowsi, hasRowsRowsi = rowsiOffset()
+
+// rowsiOffset finds the offset of the driver.Rows in sql.Rows.
+func rowsiOffset() (uintptr, bool) {
+	field, ok := reflect.TypeOf(sql.Rows{}).FieldByName("rowsi")
+	if !ok || field.Type != reflect.TypeOf((*driver.Rows)(nil)).Elem() {
+		return 0, false
+	}
+	return field.Offset, true
+}
+
+func driverRows(rowOrRows interface{}) (driver.Rows, Layout, InspectStage) {
+	dRows, stage := unwrapRows(rowOrRows)
+	if stage != StageNone {
+		return nil, Layout{}, stage
+	}
+	layout, stage := checkedRows(dRows)
+	return dRows, layout, stage
+}
+
+// unwrapRows retrieves the driver.Rows of rowOrRows without checking them.
+func unwrapRows(rowOrRows interface{}) (driver.Rows, InspectStage) {
+	if rowOrRows == nil {
+		return nil, StageNil
+	}
+	if rows, ok := rowOrRows.(*sql.Rows); ok && rows != nil && hasRowsRowsi {
+		// fast path for the common case, skips the type switch and extractors of Inspect
+		dRows := *(*driver.Rows)(unsafe.Pointer(uintptr(unsafe.Pointer(rows)) + offsetRowsRowsi))
+		if dRows == nil {
+			return nil, StageNotRows
+		}
+		return dRows, StageNone
 	}
 	rows, err := sqlinternals.Inspect(rowOrRows)
 	if err != nil || rows == nil {
-		return nil, false
+		return nil, StageNotRows
 	}
 	dRows, ok := rows.(driver.Rows)
+	if !ok || dRows == nil {
+		return nil, StageNotRows
+	}
+	return dRows, StageNone
+}
+
+// checkedRows retrieves the layout of dRows, it is validated once per type.
+func checkedRows(dRows driver.Rows) (Layout, InspectStage) {
+	if dRows == nil {
+		return Layout{}, StageNil
+	}
+	if isEmptyRows(dRows) {
+		// nothing to check, there is no result set
+		return emptyLayout, StageNone
+	}
+	rowsType := reflect.TypeOf(dRows)
+	if rowsType.Kind() != reflect.Ptr || rowsType.Elem().Kind() != reflect.Struct || reflect.ValueOf(dRows).IsNil() {
+		return Layout{}, StageNotMysql
+	}
+	layoutMutex.RLock()
+	check, checked := layoutChecks[rowsType]
+	layoutMutex.RUnlock()
+	if !checked {
+		check = checkLayout(rowsType)
+	}
+	switch check.err {
+	case nil:
+		return check.layout, StageNone
+	case errUnexpectedType:
+		return Layout{}, StageNotMysql
+	}
+	return Layout{}, StageLayoutMismatch
+}
+
+// checkLayout validates and stores the layout of rowsType.
+func checkLayout(rowsType reflect.Type) layoutCheck {
+	const errNoColumns = mysqlError("layout does not provide Columns")
+	layoutMutex.Lock()
+	defer layoutMutex.Unlock()
+	if check, checked := layoutChecks[rowsType]; checked {
+		return check
+	}
+	validate, ok := layoutValidators[rowsType.Elem().Name()]
 	if !ok {
-		return nil, false
-	}
-	if uninitialized := !structsChecked; uninitialized {
-		ok = true
-		initMutex.Lock()
-		defer initMutex.Unlock()
-		if !failedInit {
-			switch err = initOffsets(dRows); err {
-			case nil:
-				structsChecked = true
-				uninitialized = false
-			case errUnexpectedType, errUnexpectedNil:
-				ok = false
-			default:
-				failedInit = true
-				ok = false
-			}
-			if !ok {
-				return nil, false
-			}
+		// not stored, a layout may be registered later
+		return layoutCheck{err: errUnexpectedType}
+	}
+	layout, err := validate(rowsType)
+	if err == nil && layout.Columns == nil {
+		err = errNoColumns
+	}
+	if err != nil {
+		logf("mysqlinternals: unsupported layout of %v: %v", rowsType, err)
+		layout = Layout{}
+	}
+	check := layoutCheck{layout: layout, err: err}
+	layoutChecks[rowsType] = check
+	if err == nil && layout.fields != nil {
+		types, _ := uncheckedTypes.Load().([]reflect.Type)
+		uncheckedTypes.Store(append(types[:len(types):len(types)], rowsType))
+	}
+	return check
+}
+
+// isUncheckedType reports whether ColumnsUnchecked may read the fields of rows of rowsType.
+func isUncheckedType(rowsType reflect.Type) bool {
+	types, _ := uncheckedTypes.Load().([]reflect.Type)
+	for _, t := range types {
+		if t == rowsType {
+			return true
 		}
 	}
-	return dRows, true
+	return false
 }
 
 // IsBinary reports whether the row value was retrieved using the binary protocol.
@@ -265,33 +555,423 @@ func driverRows(rowOrRows interface{}) (driver.Rows, bool) {
 // matching Go type.
 // A plain Query call with only the query itself will not use the binary protocol but the
 // text protocol. The results are all strings in that case.
+//
+// With interpolateParams=true in the DSN, the driver replaces the placeholders itself
+// and sends Query calls with arguments as plain queries, so their results use the text
+// protocol and IsBinary returns false. Statements prepared with Prepare still use the
+// binary protocol. The driver.Rows type always matches the protocol of the result,
+// IsBinary reports the protocol actually used.
 func IsBinary(rowOrRows interface{}) (bool, error) {
-	const errUnavailable = mysqlError("IsBinary is not available")
-	dRows, ok := driverRows(rowOrRows)
-	if !ok {
-		return false, errUnavailable
+	dRows, layout, stage := driverRows(rowOrRows)
+	if stage != StageNone {
+		return false, columnsError("IsBinary", rowOrRows, stage)
+	}
+	return isBinary(dRows, layout), nil
+}
+
+// isBinary reports whether checked driver.Rows use the binary protocol.
+func isBinary(dRows driver.Rows, layout Layout) bool {
+	return layout.IsBinary != nil && layout.IsBinary(dRows)
+}
+
+// mysqlIsBinary reports whether go-sql-driver's rows use the binary protocol.
+func mysqlIsBinary(dRows driver.Rows) bool {
+	return rowtypeBinary == reflect.TypeOf(dRows).Elem().Name()
+}
+
+// names of integer fields in driver.Rows implementations holding the number of rows
+var rowCountFields = []string{"rowCount", "numRows", "affectedRows"}
+
+// rowCount retrieves the number of rows from the first matching integer field in rowCountFields.
+func rowCount(dRows driver.Rows) (int64, bool) {
+	v := reflect.ValueOf(dRows)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return 0, false
+	}
+	if v = v.Elem(); v.Kind() != reflect.Struct {
+		return 0, false
+	}
+	for _, name := range rowCountFields {
+		field := v.FieldByName(name)
+		switch field.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return field.Int(), true
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if n := field.Uint(); n <= math.MaxInt64 {
+				return int64(n), true
+			}
+		}
 	}
-	argType := reflect.TypeOf(dRows)
-	return rowtypeBinary == argType.Elem().Name(), nil
+	return 0, false
+}
+
+// EstimatedRowCount retrieves the number of rows in the result if the driver provides it.
+//
+// github.com/go-sql-driver/mysql streams rows and does not know their number in advance,
+// so its results always return (0, false).
+// Drivers with a layout registered by RegisterLayout keeping the number of rows in a field
+// named rowCount, numRows or affectedRows are supported.
+func EstimatedRowCount(rowOrRows interface{}) (int64, bool) {
+	dRows, _, stage := driverRows(rowOrRows)
+	if stage != StageNone {
+		return 0, false
+	}
+	return rowCount(dRows)
 }
 
 // Columns retrieves a []Column for sql.Rows or sql.Row with type inspection abilities.
 //
 // The field indices match those of a call to Columns().
-// Returns an error if the argument is not sql.Rows or sql.Row based on github.com/go-sql-driver/mysql.
+// Returns an error if the argument is not sql.Rows or sql.Row based on github.com/go-sql-driver/mysql
+// or on a driver with a layout registered by RegisterLayout.
+// The error is a *ColumnsError describing the failure.
+// Returns ErrNoResultSet if the statement has no result set, e.g. for an INSERT run with Query.
+// The driver reads the column definitions before the first row and keeps them when the rows
+// are closed, so Columns still works after Close or the cancellation of the query's context.
 func Columns(rowOrRows interface{}) ([]Column, error) {
-	const errUnavailable = mysqlError("Columns is not available")
-	dRows, ok := driverRows(rowOrRows)
+	dRows, layout, stage := driverRows(rowOrRows)
+	if stage != StageNone {
+		return nil, columnsError("Columns", rowOrRows, stage)
+	}
+	return resultColumns("Columns", rowOrRows, dRows, layout)
+}
+
+// ErrNoResultSet is returned when columns are requested for a statement without a result set.
+var ErrNoResultSet error = mysqlError("statement has no result set")
+
+// resultColumns retrieves the columns from checked driver.Rows for the function fn called with arg.
+func resultColumns(fn string, arg interface{}, dRows driver.Rows, layout Layout) (cols []Column, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			cols, err = nil, recoveredError(fn, arg, r)
+		}
+	}()
+	if layout.fields != nil {
+		// check the fields before they are copied
+		fields := layout.fields(dRows)
+		if len(fields) == 0 {
+			// MySQL results always have columns
+			return nil, ErrNoResultSet
+		}
+		if stage := checkFields(dRows, fields); stage != StageNone {
+			return nil, columnsError(fn, arg, stage)
+		}
+		return fieldColumns(fields), nil
+	}
+	cols = layout.Columns(dRows)
+	if len(cols) == 0 {
+		return nil, ErrNoResultSet
+	}
+	names := dRows.Columns()
+	if len(cols) != len(names) {
+		return nil, columnsError(fn, arg, StageColumnCountMismatch)
+	}
+	for i, col := range cols {
+		if !sameColumn(names[i], col.TableName(), col.Name()) {
+			return nil, columnsError(fn, arg, StageColumnNameMismatch)
+		}
+	}
+	return cols, nil
+}
+
+// checkFields checks the fields read from dRows against the columns reported by the driver itself.
+// A different count indicates a bogus slice header read through a layout that does not fit
+// the driver, the fields must not be accessed then. Different names indicate fields
+// that are not in the order of the columns.
+func checkFields(dRows driver.Rows, fields []mysqlField) InspectStage {
+	names := dRows.Columns()
+	if len(fields) > cap(fields) || len(fields) != len(names) {
+		return StageColumnCountMismatch
+	}
+	for i := range fields {
+		if !sameColumn(names[i], fields[i].tableName, fields[i].name) {
+			return StageColumnNameMismatch
+		}
+	}
+	return StageNone
+}
+
+// sameColumn reports whether the driver reports name for the column,
+// with columnsWithAlias=true in the DSN it prefixes the names with the table.
+func sameColumn(name, table, column string) bool {
+	return name == column || name == table+"."+column
+}
+
+// ColumnsAndProtocol retrieves the columns like Columns and reports whether
+// the binary protocol is used like IsBinary, but only inspects rowOrRows once.
+func ColumnsAndProtocol(rowOrRows interface{}) ([]Column, bool, error) {
+	dRows, layout, stage := driverRows(rowOrRows)
+	if stage != StageNone {
+		return nil, false, columnsError("ColumnsAndProtocol", rowOrRows, stage)
+	}
+	cols, err := resultColumns("ColumnsAndProtocol", rowOrRows, dRows, layout)
+	return cols, isBinary(dRows, layout), err
+}
+
+// ColumnsForCurrentResultSet retrieves the columns of the result set rows is positioned on.
+//
+// github.com/go-sql-driver/mysql reads each result set of a multi statement query
+// (multiStatements=true in the DSN) into the same driver.Rows on rows.NextResultSet(),
+// so the columns are read on every call and reflect the last call to NextResultSet.
+// The results of a stored procedure CALL are read the same way, starting with the first
+// result set; the driver keeps no indicator that they came from a procedure.
+func ColumnsForCurrentResultSet(rows *sql.Rows) ([]Column, error) {
+	if rows == nil {
+		return nil, columnsError("ColumnsForCurrentResultSet", rows, StageNil)
+	}
+	dRows, layout, stage := driverRows(rows)
+	if stage != StageNone {
+		return nil, columnsError("ColumnsForCurrentResultSet", rows, stage)
+	}
+	return resultColumns("ColumnsForCurrentResultSet", rows, dRows, layout)
+}
+
+// NamedColumns returns the column names of rows as reported by database/sql
+// together with the metadata of the same columns.
+//
+// Both slices are index aligned; if their lengths differ, the driver
+// reports inconsistent columns and an error is returned.
+func NamedColumns(rows *sql.Rows) ([]string, []Column, error) {
+	const errMismatch = mysqlError("NamedColumns: driver reports different column counts")
+	if rows == nil {
+		return nil, nil, columnsError("NamedColumns", rows, StageNil)
+	}
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+	cols, err := ColumnsForCurrentResultSet(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(names) != len(cols) {
+		return nil, nil, errMismatch
+	}
+	return names, cols, nil
+}
+
+// ReadColumnInfo writes the metadata of the columns of sql.Rows or sql.Row into dst
+// and returns the number of columns written.
+//
+// Reusing dst avoids the allocations of Columns for rows of github.com/go-sql-driver/mysql.
+// If dst is too short, it is filled and an error is returned.
+func ReadColumnInfo(dst []ColumnInfo, rowOrRows interface{}) (n int, err error) {
+	const errShortBuffer = mysqlError("ReadColumnInfo: dst is too short for all columns")
+	defer func() {
+		if r := recover(); r != nil {
+			n, err = 0, recoveredError("ReadColumnInfo", rowOrRows, r)
+		}
+	}()
+	dRows, layout, stage := driverRows(rowOrRows)
+	if stage != StageNone {
+		return 0, columnsError("ReadColumnInfo", rowOrRows, stage)
+	}
+	if layout.fields != nil {
+		fields := layout.fields(dRows)
+		if len(fields) == 0 {
+			return 0, ErrNoResultSet
+		}
+		if stage := checkFields(dRows, fields); stage != StageNone {
+			return 0, columnsError("ReadColumnInfo", rowOrRows, stage)
+		}
+		for n < len(dst) && n < len(fields) {
+			dst[n] = fields[n].info()
+			n++
+		}
+		if n < len(fields) {
+			return n, errShortBuffer
+		}
+		return n, nil
+	}
+	cols, err := resultColumns("ReadColumnInfo", rowOrRows, dRows, layout)
+	if err != nil {
+		return 0, err
+	}
+	for n < len(dst) && n < len(cols) {
+		// all implementations of Column are mysqlField
+		f, _ := cols[n].(mysqlField)
+		dst[n] = f.info()
+		n++
+	}
+	if n < len(cols) {
+		return n, errShortBuffer
+	}
+	return n, nil
+}
+
+// ColumnsFromDriverRows retrieves a []Column for driver.Rows of github.com/go-sql-driver/mysql.
+//
+// It works like Columns, but skips retrieving the driver.Rows from sql.Rows or sql.Row.
+func ColumnsFromDriverRows(dr driver.Rows) ([]Column, error) {
+	layout, stage := checkedRows(dr)
+	if stage != StageNone {
+		return nil, columnsError("ColumnsFromDriverRows", dr, stage)
+	}
+	return resultColumns("ColumnsFromDriverRows", dr, dr, layout)
+}
+
+// ColumnsFromInspected retrieves a []Column for the result of sqlinternals.Inspect
+// on sql.Rows or sql.Row of github.com/go-sql-driver/mysql.
+//
+// It works like ColumnsFromDriverRows, but accepts the interface{} returned by Inspect.
+func ColumnsFromInspected(inspected interface{}) ([]Column, error) {
+	if inspected == nil {
+		return nil, columnsError("ColumnsFromInspected", inspected, StageNil)
+	}
+	dr, ok := inspected.(driver.Rows)
 	if !ok {
-		return nil, errUnavailable
+		return nil, columnsError("ColumnsFromInspected", inspected, StageNotRows)
+	}
+	layout, stage := checkedRows(dr)
+	if stage != StageNone {
+		return nil, columnsError("ColumnsFromInspected", inspected, stage)
+	}
+	return resultColumns("ColumnsFromInspected", inspected, dr, layout)
+}
+
+// ErrNoParameterMetadata is returned by Parameters.
+var ErrNoParameterMetadata error = mysqlError("the driver discards the metadata of statement parameters")
+
+// ErrNoColumnMetadata is returned by ColumnsFromStmt for driver versions not keeping the
+// column definitions of prepared statements; the statement may still have a result set.
+var ErrNoColumnMetadata error = mysqlError("the driver discards the metadata of statement columns")
+
+// Parameters is meant to return the metadata of the parameters of a prepared statement.
+//
+// MySQL sends a definition packet for each parameter in the response to COM_STMT_PREPARE,
+// but github.com/go-sql-driver/mysql skips these packets and only keeps their count,
+// which is available through NumInput of the driver.Stmt.
+// Parameters always returns ErrNoParameterMetadata for a non-nil stmt
+// until a driver version retains the definitions.
+func Parameters(stmt *sql.Stmt) ([]Column, error) {
+	if stmt == nil {
+		return nil, columnsError("Parameters", stmt, StageNil)
 	}
-	if rowtypeEmpty == reflect.TypeOf(dRows).Name() {
-		return nil, nil
+	return nil, ErrNoParameterMetadata
+}
+
+// ServerVersion is meant to return the version the server sent in the handshake
+// for the connection of the rows, e.g. to interpret type codes that differ between versions.
+//
+// github.com/go-sql-driver/mysql skips the version in the handshake packet and
+// only keeps the capability flags, so it is not reachable from the rows.
+// ServerVersion always returns "" and false until a driver version retains it;
+// query "SELECT VERSION()" on the same connection instead.
+func ServerVersion(rowOrRows interface{}) (string, bool) {
+	return "", false
+}
+
+// ColumnsUnchecked works like Columns for sql.Rows or sql.Row of github.com/go-sql-driver/mysql,
+// but skips the type check of the driver.Rows.
+//
+// It returns a *ColumnsError with StageNotValidated until Columns or a related function
+// validated the layout of the type of the driver.Rows successfully; each type, e.g. the
+// driver's textRows and binaryRows, is validated separately. Rows of other drivers and
+// layouts failing the validation always return StageNotValidated.
+//
+// Sharp edges: only use it when all rows passed to it are created by github.com/go-sql-driver/mysql
+// with the validated layout. The fields of the rows are read through that layout without locking
+// and without comparing them with the columns reported by the driver, e.g. columns differing
+// in their count or names are returned as they are.
+func ColumnsUnchecked(rowOrRows interface{}) (cols []Column, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			cols, err = nil, recoveredError("ColumnsUnchecked", rowOrRows, r)
+		}
+	}()
+	dRows, stage := unwrapRows(rowOrRows)
+	if stage != StageNone {
+		return nil, columnsError("ColumnsUnchecked", rowOrRows, stage)
 	}
-	cols := (*mysqlRows)((unsafe.Pointer)(reflect.ValueOf(dRows).Pointer())).rs.columns
-	columns := make([]Column, len(cols))
-	for i, c := range cols {
-		columns[i] = c
+	if !isUncheckedType(reflect.TypeOf(dRows)) {
+		if isEmptyRows(dRows) {
+			return nil, ErrNoResultSet
+		}
+		return nil, columnsError("ColumnsUnchecked", rowOrRows, StageNotValidated)
+	}
+	fields := mysqlFields(dRows)
+	if len(fields) == 0 {
+		return nil, ErrNoResultSet
+	}
+	return fieldColumns(fields), nil
+}
+
+// mysqlColumns retrieves the columns from go-sql-driver's checked textRows or binaryRows.
+func mysqlColumns(dRows driver.Rows) []Column {
+	return fieldColumns(mysqlFields(dRows))
+}
+
+// mysqlFields retrieves the fields from go-sql-driver's checked textRows or binaryRows.
+func mysqlFields(dRows driver.Rows) []mysqlField {
+	return (*mysqlRows)((unsafe.Pointer)(reflect.ValueOf(dRows).Pointer())).rs.columns
+}
+
+// fieldColumns converts the fields to columns.
+func fieldColumns(fields []mysqlField) []Column {
+	columns := make([]Column, len(fields))
+	for i, f := range fields {
+		columns[i] = f
+	}
+	return columns
+}
+
+// ColumnsBestEffort works like Columns, but also reads the columns of textRows and binaryRows
+// with an unsupported layout if mysqlField still matches.
+//
+// The columns are searched by the field name "columns" in the rows and their nested structs.
+// degraded reports that the layout was not validated and the columns were found that way.
+// The columns found are checked against the columns the driver reports like in Columns.
+func ColumnsBestEffort(rowOrRows interface{}) (cols []Column, degraded bool, err error) {
+	dRows, layout, stage := driverRows(rowOrRows)
+	switch stage {
+	case StageNone:
+		cols, err := resultColumns("ColumnsBestEffort", rowOrRows, dRows, layout)
+		return cols, false, err
+	case StageLayoutMismatch:
+		if cols, found, err := foundColumns(rowOrRows, dRows); found {
+			return cols, err == nil, err
+		}
+	}
+	return nil, false, columnsError("ColumnsBestEffort", rowOrRows, stage)
+}
+
+// foundColumns retrieves the columns of dRows with findFields for ColumnsBestEffort.
+// found reports whether the fields were found; they are checked like in resultColumns,
+// as the layout they are read through was not validated.
+func foundColumns(arg interface{}, dRows driver.Rows) (cols []Column, found bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			cols, found, err = nil, true, recoveredError("ColumnsBestEffort", arg, r)
+		}
+	}()
+	fields, ok := findFields(reflect.ValueOf(dRows).Elem(), 3)
+	if !ok {
+		return nil, false, nil
+	}
+	if len(fields) == 0 {
+		return nil, true, ErrNoResultSet
+	}
+	if stage := checkFields(dRows, fields); stage != StageNone {
+		return nil, true, columnsError("ColumnsBestEffort", arg, stage)
+	}
+	return fieldColumns(fields), true, nil
+}
+
+// findFields searches the addressable struct v and its nested structs up to depth levels
+// for a slice named columns with elements matching mysqlField.
+func findFields(v reflect.Value, depth int) ([]mysqlField, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		switch {
+		case sf.Name == "columns" && sf.Type.Kind() == reflect.Slice &&
+			canConvert(sf.Type.Elem(), reflect.TypeOf(mysqlField{})):
+			return *(*[]mysqlField)(unsafe.Pointer(v.Field(i).UnsafeAddr())), true
+		case sf.Type.Kind() == reflect.Struct && depth > 0:
+			if fields, ok := findFields(v.Field(i), depth-1); ok {
+				return fields, true
+			}
+		}
 	}
-	return columns, nil
+	return nil, false
 }
@@ -9,6 +9,7 @@
 package sqlinternals
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"io"
@@ -103,8 +104,13 @@ func runRowsTest(t *testing.T, query querier, numInputs int, columns []string, c
 	conn, err := sql.Open(driverType, "")
 	defer conn.Close()
 	rowOrRows, err := query(conn)
-	if closer, ok := rowOrRows.(io.Closer); ok {
-		defer closer.Close()
+	switch r := rowOrRows.(type) {
+	case io.Closer:
+		defer r.Close()
+	case *sql.Row:
+		// Scan closes the rows of the row and releases its connection
+		var discard interface{}
+		defer r.Scan(&discard)
 	}
 	// check that it is accessible and matches the one in testdriver.rows
 	unwrapped, err := Inspect(rowOrRows)
@@ -145,3 +151,113 @@ func TestRowsWithArgs(t *testing.T) {
 	}
 	runRowsTest(t, query, 1, []string{"header"}, "test")
 }
+
+func TestRowInTransaction(t *testing.T) {
+	var tx *sql.Tx
+	defer func() {
+		if tx != nil {
+			tx.Rollback()
+		}
+	}()
+	query := func(conn *sql.DB) (interface{}, error) {
+		var err error
+		if tx, err = conn.Begin(); err != nil {
+			return nil, err
+		}
+		return tx.QueryRow(`SELECT ?`, "test"), nil
+	}
+	runRowsTest(t, query, 1, []string{"header"}, "test")
+}
+
+func TestRowOnConn(t *testing.T) {
+	var conn *sql.Conn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+	query := func(db *sql.DB) (interface{}, error) {
+		var err error
+		if conn, err = db.Conn(context.Background()); err != nil {
+			return nil, err
+		}
+		return conn.QueryRowContext(context.Background(), `SELECT ?`, "test"), nil
+	}
+	runRowsTest(t, query, 1, []string{"header"}, "test")
+}
+
+func TestRowsOnConn(t *testing.T) {
+	var conn *sql.Conn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+	query := func(db *sql.DB) (interface{}, error) {
+		var err error
+		if conn, err = db.Conn(context.Background()); err != nil {
+			return nil, err
+		}
+		return conn.QueryContext(context.Background(), `SELECT ?`, "test")
+	}
+	runRowsTest(t, query, 1, []string{"header"}, "test")
+}
+
+func TestRowsInTransaction(t *testing.T) {
+	var tx *sql.Tx
+	defer func() {
+		if tx != nil {
+			tx.Rollback()
+		}
+	}()
+	query := func(conn *sql.DB) (interface{}, error) {
+		var err error
+		if tx, err = conn.Begin(); err != nil {
+			return nil, err
+		}
+		return tx.Query(`SELECT ?`, "test")
+	}
+	runRowsTest(t, query, 1, []string{"header"}, "test")
+}
+
+// wrappedRows mimics the result type of a query building library
+type wrappedRows struct {
+	Rows *sql.Rows
+}
+
+func TestRegisterRowsExtractor(t *testing.T) {
+	testdriver.setDB(0, []string{"header"}, "test")
+	conn, err := sql.Open(driverType, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	rows, err := conn.Query(`SELECT "test"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	wrapped := wrappedRows{Rows: rows}
+	if unwrapped, _ := Inspect(wrapped); unwrapped != errArgWrongType {
+		t.Errorf("unregistered wrapper must be rejected, got %v\n", unwrapped)
+	}
+	unregister := RegisterRowsExtractor(func(v interface{}) (*sql.Rows, bool) {
+		w, ok := v.(wrappedRows)
+		return w.Rows, ok
+	})
+	defer unregister()
+	unwrapped, err := Inspect(wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if myrows, ok := unwrapped.(*omnithing); !ok || myrows != testdriver {
+		t.Errorf("returned driver.Rows must match those passed in.\n")
+	}
+	if unwrapped, _ := Inspect(struct{}{}); unwrapped != errArgWrongType {
+		t.Errorf("unknown type must be rejected, got %v\n", unwrapped)
+	}
+	unregister()
+	if unwrapped, _ := Inspect(wrapped); unwrapped != errArgWrongType {
+		t.Errorf("unregistered wrapper must be rejected, got %v\n", unwrapped)
+	}
+}
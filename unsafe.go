@@ -12,6 +12,7 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"reflect"
+	"sync"
 	"unsafe"
 )
 
@@ -21,6 +22,17 @@ var (
 	offsetRowsRowsi uintptr // sql.Rows.rowsi: driver.Rows
 )
 
+var (
+	// extractors for rows wrapped by other libraries, see RegisterRowsExtractor
+	extractorsMu sync.RWMutex
+	extractors   []*rowsExtractor
+)
+
+// rowsExtractor wraps a registered extractor, the pointer identifies it for removal
+type rowsExtractor struct {
+	extract func(interface{}) (*sql.Rows, bool)
+}
+
 // internal error type
 type internalErr string
 
@@ -97,7 +109,45 @@ func init() {
 	}
 }
 
+// RegisterRowsExtractor registers a function retrieving sql.*Rows from result types
+// of other libraries wrapping them. Inspect tries the extractors in registration order
+// for arguments that are neither sql.*Row nor sql.*Rows.
+// An extractor returns false for values it does not handle.
+// The returned function removes the registration, e.g. to undo it in tests.
+func RegisterRowsExtractor(extract func(interface{}) (*sql.Rows, bool)) (unregister func()) {
+	if extract == nil {
+		return func() {}
+	}
+	registered := &rowsExtractor{extract: extract}
+	extractorsMu.Lock()
+	extractors = append(extractors, registered)
+	extractorsMu.Unlock()
+	return func() {
+		extractorsMu.Lock()
+		defer extractorsMu.Unlock()
+		for i, e := range extractors {
+			if e == registered {
+				extractors = append(extractors[:i], extractors[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// extractRows retrieves sql.*Rows with the first matching registered extractor.
+func extractRows(v interface{}) (*sql.Rows, bool) {
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+	for _, e := range extractors {
+		if rows, ok := e.extract(v); ok && rows != nil {
+			return rows, true
+		}
+	}
+	return nil, false
+}
+
 // Inspect extracts the internal driver.Rows from sql.*Row or sql.*Rows.
+// Other types are passed to the extractors registered with RegisterRowsExtractor.
 // This can be used by a driver to work around issue 5606 in Go until a better way exists.
 func Inspect(sqlStruct interface{}) (interface{}, error) {
 	// All of this has to use unsafe to access unexported fields, but it's robust:
@@ -118,7 +168,11 @@ func Inspect(sqlStruct interface{}) (interface{}, error) {
 	case *sql.Rows:
 		rows = v
 	default:
-		return errArgWrongType, nil
+		extracted, ok := extractRows(v)
+		if !ok {
+			return errArgWrongType, nil
+		}
+		rows = extracted
 	}
 	// return rowsi from sql.*Rows, if rows.rowsi is nil an error is returned.
 	rowsiPtr := offsetRowsRowsi + (uintptr)((unsafe.Pointer)(rows))